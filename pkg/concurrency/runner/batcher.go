@@ -0,0 +1,212 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrBatcherClosed is returned when adding an item to a [Batcher] that has already been closed.
+var ErrBatcherClosed = errors.New("batcher closed")
+
+// ErrBatchResultLength is reported for every item in a batch whose [BatchFunc] returned a non-nil
+// error slice with a length different from the number of items in the batch.
+var ErrBatchResultLength = errors.New("batch function returned wrong number of errors")
+
+// BatchFunc processes a batch of items.
+//
+// If the returned slice is nil, every item in the batch is considered to have succeeded. Otherwise,
+// the slice must have the same length as items, and each non-nil element is reported as the error
+// for the item at the same index.
+type BatchFunc[T any] func(items []T) []error
+
+// ItemError associates an error with the [Batcher] item that produced it.
+type ItemError[T any] struct {
+	Item T
+	Err  error
+}
+
+// Error implements the error interface.
+func (e *ItemError[T]) Error() string {
+	return fmt.Sprintf("batch item %v: %v", e.Item, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ItemError[T]) Unwrap() error {
+	return e.Err
+}
+
+// Batcher collects items passed to [Batcher.Add] and invokes a [BatchFunc] with batches of them.
+// A batch is dispatched once it reaches the maximum batch size, or once the maximum delay has
+// elapsed since the first item was added to it, whichever comes first.
+//
+// Batches are executed as tasks of an underlying [Runner], so they run concurrently and respect
+// any options (such as [WithLimit]) passed to [NewBatcher]. When the limit is reached, dispatching
+// a batch blocks, which in turn applies backpressure to [Batcher.Add].
+//
+// This struct should not be directly instantiated; callers should use the [NewBatcher] function
+// instead.
+type Batcher[T any] struct {
+	runner   *Runner
+	fn       BatchFunc[T]
+	maxSize  int
+	maxDelay time.Duration
+
+	mutex   sync.Mutex
+	pending []T
+	timer   *time.Timer
+	// gen is incremented every time the pending batch is taken, so a delay timer firing late can
+	// tell that the batch it was started for has already been dispatched.
+	gen    uint64
+	closed bool
+	// dispatching tracks batches that have been taken from pending but not yet handed to the
+	// runner, so Close doesn't wait on the runner before they're registered with it.
+	dispatching sync.WaitGroup
+	errs        syncErrorSlice
+}
+
+// NewBatcher returns a new Batcher that dispatches batches of up to maxSize items to fn. If maxDelay
+// is positive, a partially filled batch is dispatched once maxDelay has elapsed since its first
+// item was added; otherwise, partial batches are only dispatched by [Batcher.Flush] and
+// [Batcher.Close].
+//
+// The provided options configure the underlying [Runner]. NewBatcher panics if maxSize is not
+// positive.
+func NewBatcher[T any](
+	ctx context.Context, maxSize int, maxDelay time.Duration, fn BatchFunc[T], opts ...Option,
+) *Batcher[T] {
+	if maxSize <= 0 {
+		panic("runner: non-positive maxSize for NewBatcher")
+	}
+	return &Batcher[T]{
+		runner:   New(ctx, opts...),
+		fn:       fn,
+		maxSize:  maxSize,
+		maxDelay: maxDelay,
+	}
+}
+
+// Add adds an item to the current batch, dispatching the batch if it has become full. If the
+// Batcher has been closed, ErrBatcherClosed is returned.
+func (b *Batcher[T]) Add(item T) error {
+	b.mutex.Lock()
+	if b.closed {
+		b.mutex.Unlock()
+		return ErrBatcherClosed
+	}
+	b.pending = append(b.pending, item)
+	if len(b.pending) == 1 && b.maxDelay > 0 {
+		gen := b.gen
+		b.timer = time.AfterFunc(b.maxDelay, func() {
+			b.flushGen(gen)
+		})
+	}
+	if len(b.pending) < b.maxSize {
+		b.mutex.Unlock()
+		return nil
+	}
+	items := b.takeLocked()
+	b.mutex.Unlock()
+
+	b.dispatch(items)
+	return nil
+}
+
+// Flush dispatches the current batch immediately, even if it is not full. If there are no pending
+// items, this is a no-op.
+func (b *Batcher[T]) Flush() {
+	b.mutex.Lock()
+	items := b.takeLocked()
+	b.mutex.Unlock()
+
+	b.dispatch(items)
+}
+
+// Close dispatches any pending items, waits for all batches to finish, and returns the errors for
+// every failed item. Each returned error is an [*ItemError]. Items in batches that were skipped
+// because the Runner's context was done are reported with the skip reason.
+//
+// After Close is called, subsequent calls to [Batcher.Add] return ErrBatcherClosed.
+func (b *Batcher[T]) Close() []error {
+	b.mutex.Lock()
+	b.closed = true
+	items := b.takeLocked()
+	b.mutex.Unlock()
+
+	b.dispatch(items)
+	b.dispatching.Wait()
+	_ = b.runner.Wait()
+	return b.errs.Clone()
+}
+
+// flushGen dispatches the pending batch if it is still the batch of the given generation.
+func (b *Batcher[T]) flushGen(gen uint64) {
+	b.mutex.Lock()
+	if b.gen != gen {
+		b.mutex.Unlock()
+		return
+	}
+	items := b.takeLocked()
+	b.mutex.Unlock()
+
+	b.dispatch(items)
+}
+
+// takeLocked removes and returns the pending batch, registering it with the dispatching wait
+// group. It returns nil if there are no pending items. The caller must hold the mutex, and must
+// pass the result to dispatch.
+func (b *Batcher[T]) takeLocked() []T {
+	if len(b.pending) == 0 {
+		return nil
+	}
+	items := b.pending
+	b.pending = nil
+	b.gen++
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.dispatching.Add(1)
+	return items
+}
+
+// dispatch runs the batch function for the given items via the underlying Runner. A nil batch is
+// ignored.
+func (b *Batcher[T]) dispatch(items []T) {
+	if items == nil {
+		return
+	}
+	defer b.dispatching.Done()
+
+	b.runner.goTask(func() error {
+		errs := b.fn(items)
+		if errs == nil {
+			return nil
+		}
+		if len(errs) != len(items) {
+			b.failAll(items, ErrBatchResultLength)
+			return ErrBatchResultLength
+		}
+		var failed []error
+		for i, err := range errs {
+			if err == nil {
+				continue
+			}
+			itemErr := &ItemError[T]{Item: items[i], Err: err}
+			b.errs.Append(itemErr)
+			failed = append(failed, itemErr)
+		}
+		return errors.Join(failed...)
+	}, func(cause error) {
+		b.failAll(items, cause)
+	})
+}
+
+// failAll records the same error for every item in the batch.
+func (b *Batcher[T]) failAll(items []T, err error) {
+	for _, item := range items {
+		b.errs.Append(&ItemError[T]{Item: item, Err: err})
+	}
+}
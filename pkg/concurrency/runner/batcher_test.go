@@ -0,0 +1,231 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBatcherBatchesBySize(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name          string
+		maxSize       int
+		totalItems    int
+		wantBatchLens []int
+	}{
+		{
+			name:          "exact_multiple",
+			maxSize:       4,
+			totalItems:    8,
+			wantBatchLens: []int{4, 4},
+		},
+		{
+			name:          "partial_final_batch",
+			maxSize:       4,
+			totalItems:    10,
+			wantBatchLens: []int{2, 4, 4},
+		},
+		{
+			name:          "max_size_1",
+			maxSize:       1,
+			totalItems:    3,
+			wantBatchLens: []int{1, 1, 1},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mutex sync.Mutex
+			var gotBatchLens []int
+			var gotItems []int
+			b := NewBatcher(context.Background(), tc.maxSize, 0, func(items []int) []error {
+				mutex.Lock()
+				defer mutex.Unlock()
+				gotBatchLens = append(gotBatchLens, len(items))
+				gotItems = append(gotItems, items...)
+				return nil
+			})
+
+			for i := range tc.totalItems {
+				if err := b.Add(i); err != nil {
+					t.Fatalf("Add(%d) failed with error %v", i, err)
+				}
+			}
+			if errs := b.Close(); len(errs) != 0 {
+				t.Fatalf("Close() returned errors %v, want none", messages(errs))
+			}
+
+			slices.Sort(gotBatchLens)
+			if !slices.Equal(gotBatchLens, tc.wantBatchLens) {
+				t.Errorf("got batch lengths %v, want %v", gotBatchLens, tc.wantBatchLens)
+			}
+			slices.Sort(gotItems)
+			wantItems := make([]int, 0, tc.totalItems)
+			for i := range tc.totalItems {
+				wantItems = append(wantItems, i)
+			}
+			if !slices.Equal(gotItems, wantItems) {
+				t.Errorf("got items %v across all batches, want %v", gotItems, wantItems)
+			}
+		})
+	}
+}
+
+func TestBatcherFlushesAfterMaxDelay(t *testing.T) {
+	t.Parallel()
+
+	flushed := make(chan []string, 1)
+	b := NewBatcher(context.Background(), 100, 10*time.Millisecond, func(items []string) []error {
+		flushed <- items
+		return nil
+	})
+
+	if err := b.Add("a"); err != nil {
+		t.Fatalf("Add() failed with error %v", err)
+	}
+	if err := b.Add("b"); err != nil {
+		t.Fatalf("Add() failed with error %v", err)
+	}
+
+	select {
+	case got := <-flushed:
+		if want := []string{"a", "b"}; !slices.Equal(got, want) {
+			t.Errorf("got batch %v, want %v", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("batch was not dispatched after max delay")
+	}
+	if errs := b.Close(); len(errs) != 0 {
+		t.Fatalf("Close() returned errors %v, want none", messages(errs))
+	}
+}
+
+func TestBatcherPerItemErrors(t *testing.T) {
+	t.Parallel()
+
+	errOdd := errors.New("odd item")
+	b := NewBatcher(context.Background(), 3, 0, func(items []int) []error {
+		errs := make([]error, len(items))
+		for i, item := range items {
+			if item%2 == 1 {
+				errs[i] = errOdd
+			}
+		}
+		return errs
+	})
+	for i := range 6 {
+		_ = b.Add(i)
+	}
+	errs := b.Close()
+
+	var gotFailed []int
+	for _, err := range errs {
+		var itemErr *ItemError[int]
+		if !errors.As(err, &itemErr) {
+			t.Fatalf("Close() returned error %v of type %T, want *ItemError[int]", err, err)
+		}
+		if !errors.Is(err, errOdd) {
+			t.Errorf("Close() returned error %v, want it to wrap %v", err, errOdd)
+		}
+		gotFailed = append(gotFailed, itemErr.Item)
+	}
+	slices.Sort(gotFailed)
+	if want := []int{1, 3, 5}; !slices.Equal(gotFailed, want) {
+		t.Errorf("got failed items %v, want %v", gotFailed, want)
+	}
+}
+
+func TestBatcherWrongResultLength(t *testing.T) {
+	t.Parallel()
+
+	b := NewBatcher(context.Background(), 2, 0, func(items []int) []error {
+		return []error{nil}
+	})
+	_ = b.Add(1)
+	_ = b.Add(2)
+	errs := b.Close()
+
+	if len(errs) != 2 {
+		t.Fatalf("Close() returned %d errors, want 2; errs: %v", len(errs), messages(errs))
+	}
+	for _, err := range errs {
+		if !errors.Is(err, ErrBatchResultLength) {
+			t.Errorf("Close() returned error %v, want %v", err, ErrBatchResultLength)
+		}
+	}
+}
+
+func TestBatcherAddAfterClose(t *testing.T) {
+	t.Parallel()
+
+	b := NewBatcher(context.Background(), 2, 0, func(items []int) []error {
+		return nil
+	})
+	_ = b.Close()
+
+	if err := b.Add(1); !errors.Is(err, ErrBatcherClosed) {
+		t.Errorf("Add() after Close() got error %v, want %v", err, ErrBatcherClosed)
+	}
+}
+
+func TestBatcherSkippedBatchesReportContextError(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := atomic.Bool{}
+	b := NewBatcher(ctx, 2, 0, func(items []int) []error {
+		called.Store(true)
+		return nil
+	})
+	for i := range 3 {
+		_ = b.Add(i)
+	}
+	errs := b.Close()
+
+	if called.Load() {
+		t.Errorf("batch function was called after context was canceled")
+	}
+	if len(errs) != 3 {
+		t.Fatalf("Close() returned %d errors, want 3; errs: %v", len(errs), messages(errs))
+	}
+	for _, err := range errs {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Close() returned error %v, want %v", err, context.Canceled)
+		}
+	}
+}
+
+func TestBatcherRespectsLimit(t *testing.T) {
+	t.Parallel()
+
+	limit := 2
+	var running, maxRunning atomic.Int32
+	b := NewBatcher(context.Background(), 1, 0, func(items []int) []error {
+		n := running.Add(1)
+		for {
+			cur := maxRunning.Load()
+			if n <= cur || maxRunning.CompareAndSwap(cur, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+		return nil
+	}, WithLimit(uint(limit)))
+	for i := range 32 {
+		_ = b.Add(i)
+	}
+	_ = b.Close()
+
+	if got := maxRunning.Load(); got > int32(limit) {
+		t.Errorf("observed %d concurrent batches, want at most %d", got, limit)
+	}
+}
@@ -0,0 +1,166 @@
+package runner
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned for tasks that were not run because the Runner's circuit breaker was
+// open. See [WithCircuitBreaker].
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// defaultCircuitBreakerWindow is the number of recent task results over which the failure ratio is
+// computed if [WithCircuitBreakerWindow] is not provided.
+const defaultCircuitBreakerWindow = 10
+
+// CircuitState is the state of a Runner's circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed is the normal state, in which all tasks are run.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen is the state in which tasks fail fast with [ErrCircuitOpen] without being run.
+	CircuitOpen
+	// CircuitHalfOpen is the state after the cooldown has elapsed, in which a single probe task is
+	// run to determine whether the circuit should close again.
+	CircuitHalfOpen
+)
+
+// String returns a human-readable name for the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// circuitBreaker tracks the results of recent tasks and decides whether new tasks may run.
+type circuitBreaker struct {
+	threshold float64
+	cooldown  time.Duration
+	clock     Clock
+	onChange  func(from, to CircuitState)
+
+	mutex sync.Mutex
+	state CircuitState
+	// results is a ring buffer of the most recent task results while closed; true means failure.
+	results  []bool
+	next     int
+	filled   int
+	failures int
+	openedAt time.Time
+	// probing is true while the half-open probe task is running.
+	probing bool
+}
+
+func newCircuitBreaker(o *options, clock Clock) *circuitBreaker {
+	window := o.CircuitBreakerWindow
+	if window == 0 {
+		window = defaultCircuitBreakerWindow
+	}
+	return &circuitBreaker{
+		threshold: o.CircuitBreakerThreshold,
+		cooldown:  o.CircuitBreakerCooldown,
+		clock:     clock,
+		onChange:  o.CircuitStateCallback,
+		results:   make([]bool, window),
+	}
+}
+
+// Allow reports whether a task may run. If it returns true, the caller must report the task's
+// result via Record, passing along the returned probe value.
+func (b *circuitBreaker) Allow() (allowed, probe bool) {
+	b.mutex.Lock()
+	var from CircuitState
+	changed := false
+	switch b.state {
+	case CircuitClosed:
+		allowed = true
+	case CircuitOpen:
+		if b.clock.Now().Sub(b.openedAt) >= b.cooldown {
+			from, changed = b.setStateLocked(CircuitHalfOpen)
+			b.probing = true
+			allowed, probe = true, true
+		}
+	case CircuitHalfOpen:
+		if !b.probing {
+			b.probing = true
+			allowed, probe = true, true
+		}
+	}
+	b.mutex.Unlock()
+
+	if changed {
+		b.notify(from, CircuitHalfOpen)
+	}
+	return allowed, probe
+}
+
+// Record records the result of a task that was allowed to run.
+func (b *circuitBreaker) Record(failed, probe bool) {
+	b.mutex.Lock()
+	var from, to CircuitState
+	changed := false
+	switch {
+	case probe:
+		b.probing = false
+		if failed {
+			to = CircuitOpen
+		} else {
+			to = CircuitClosed
+		}
+		from, changed = b.setStateLocked(to)
+	case b.state == CircuitClosed:
+		if b.filled == len(b.results) && b.results[b.next] {
+			b.failures--
+		}
+		b.results[b.next] = failed
+		b.next = (b.next + 1) % len(b.results)
+		b.filled = min(b.filled+1, len(b.results))
+		if failed {
+			b.failures++
+		}
+		if b.filled == len(b.results) && float64(b.failures)/float64(b.filled) >= b.threshold {
+			to = CircuitOpen
+			from, changed = b.setStateLocked(to)
+		}
+	}
+	b.mutex.Unlock()
+
+	if changed {
+		b.notify(from, to)
+	}
+}
+
+// setStateLocked transitions to the given state, returning the previous state and whether it
+// changed. The caller must hold the mutex.
+func (b *circuitBreaker) setStateLocked(to CircuitState) (CircuitState, bool) {
+	from := b.state
+	if from == to {
+		return from, false
+	}
+	b.state = to
+	switch to {
+	case CircuitOpen:
+		b.openedAt = b.clock.Now()
+	case CircuitClosed:
+		clear(b.results)
+		b.next, b.filled, b.failures = 0, 0, 0
+	}
+	return from, true
+}
+
+// notify invokes the state change callback, if one was provided. It must not be called while
+// holding the mutex, so the callback may safely interact with the Runner.
+func (b *circuitBreaker) notify(from, to CircuitState) {
+	if b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
@@ -0,0 +1,211 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stateRecorder records circuit breaker state transitions.
+type stateRecorder struct {
+	mutex       sync.Mutex
+	transitions []string
+}
+
+func (s *stateRecorder) Record(from, to CircuitState) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.transitions = append(s.transitions, from.String()+"->"+to.String())
+}
+
+func (s *stateRecorder) Transitions() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return slices.Clone(s.transitions)
+}
+
+// runOne runs a single task to completion and returns its error, if any.
+func runOne(r *Runner, f func() error) error {
+	before := len(r.Wait())
+	r.Go(f)
+	errs := r.Wait()
+	if len(errs) == before {
+		return nil
+	}
+	return errs[len(errs)-1]
+}
+
+func TestCircuitBreakerOpensAtThreshold(t *testing.T) {
+	t.Parallel()
+
+	errTask := errors.New("task failed")
+	recorder := &stateRecorder{}
+	r := New(
+		context.Background(),
+		WithClock(newFakeClock()),
+		WithCircuitBreaker(0.5, time.Minute),
+		WithCircuitBreakerWindow(4),
+		WithCircuitStateCallback(recorder.Record),
+	)
+
+	// 2 failures out of the last 4 results reaches the 0.5 threshold, but only once the window is
+	// full.
+	results := []error{errTask, nil, errTask}
+	for _, result := range results {
+		if err := runOne(r, func() error { return result }); err != result {
+			t.Fatalf("task got error %v, want %v", err, result)
+		}
+	}
+	if got := recorder.Transitions(); len(got) != 0 {
+		t.Fatalf("circuit changed state before window was full; transitions: %v", got)
+	}
+	_ = runOne(r, func() error { return nil })
+
+	called := atomic.Bool{}
+	err := runOne(r, func() error {
+		called.Store(true)
+		return nil
+	})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("task got error %v, want %v", err, ErrCircuitOpen)
+	}
+	if called.Load() {
+		t.Errorf("task function was called while circuit was open")
+	}
+	if got, want := recorder.Transitions(), []string{"closed->open"}; !slices.Equal(got, want) {
+		t.Errorf("got transitions %v, want %v", got, want)
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbe(t *testing.T) {
+	t.Parallel()
+
+	errTask := errors.New("task failed")
+
+	for _, tc := range []struct {
+		name            string
+		probeResult     error
+		wantTransitions []string
+		wantNextErr     error
+	}{
+		{
+			name:            "probe_succeeds",
+			probeResult:     nil,
+			wantTransitions: []string{"closed->open", "open->half-open", "half-open->closed"},
+			wantNextErr:     nil,
+		},
+		{
+			name:            "probe_fails",
+			probeResult:     errTask,
+			wantTransitions: []string{"closed->open", "open->half-open", "half-open->open"},
+			wantNextErr:     ErrCircuitOpen,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			clock := newFakeClock()
+			recorder := &stateRecorder{}
+			r := New(
+				context.Background(),
+				WithClock(clock),
+				WithCircuitBreaker(1, time.Minute),
+				WithCircuitBreakerWindow(1),
+				WithCircuitStateCallback(recorder.Record),
+			)
+
+			_ = runOne(r, func() error { return errTask })
+			clock.Advance(time.Minute - time.Nanosecond)
+			if err := runOne(r, func() error { return nil }); !errors.Is(err, ErrCircuitOpen) {
+				t.Fatalf("task before cooldown got error %v, want %v", err, ErrCircuitOpen)
+			}
+
+			clock.Advance(time.Nanosecond)
+			if err := runOne(r, func() error { return tc.probeResult }); err != tc.probeResult {
+				t.Fatalf("probe task got error %v, want %v", err, tc.probeResult)
+			}
+			if err := runOne(r, func() error { return nil }); err != tc.wantNextErr {
+				t.Errorf("task after probe got error %v, want %v", err, tc.wantNextErr)
+			}
+			if got := recorder.Transitions(); !slices.Equal(got, tc.wantTransitions) {
+				t.Errorf("got transitions %v, want %v", got, tc.wantTransitions)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerSingleProbeWhileHalfOpen(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	r := New(
+		context.Background(),
+		WithClock(clock),
+		WithCircuitBreaker(1, time.Minute),
+		WithCircuitBreakerWindow(1),
+	)
+	_ = runOne(r, func() error { return errors.New("task failed") })
+	clock.Advance(time.Minute)
+
+	probeStarted := make(chan struct{})
+	release := make(chan struct{})
+	r.Go(func() error {
+		close(probeStarted)
+		<-release
+		return nil
+	})
+	<-probeStarted
+
+	called := atomic.Bool{}
+	r.Go(func() error {
+		called.Store(true)
+		return nil
+	})
+	// Wait would block on the probe, so poll for the second task's error instead.
+	waitFor(t, func() bool {
+		return len(r.errs.Clone()) == 2
+	})
+	close(release)
+	errs := r.Wait()
+
+	if called.Load() {
+		t.Errorf("second task was run while the probe was running")
+	}
+	if !errors.Is(errs[len(errs)-1], ErrCircuitOpen) {
+		t.Errorf("second task got error %v, want %v", errs[len(errs)-1], ErrCircuitOpen)
+	}
+}
+
+func TestWithCircuitBreakerPanicsOnInvalidArguments(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name      string
+		threshold float64
+		cooldown  time.Duration
+	}{
+		{name: "zero_threshold", threshold: 0, cooldown: time.Minute},
+		{name: "negative_threshold", threshold: -0.5, cooldown: time.Minute},
+		{name: "threshold_above_one", threshold: 1.5, cooldown: time.Minute},
+		{name: "nan_threshold", threshold: math.NaN(), cooldown: time.Minute},
+		{name: "negative_cooldown", threshold: 0.5, cooldown: -time.Minute},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			defer func() {
+				if recover() == nil {
+					t.Errorf("WithCircuitBreaker(%v, %v) did not panic, but should have", tc.threshold, tc.cooldown)
+				}
+			}()
+			WithCircuitBreaker(tc.threshold, tc.cooldown)
+		})
+	}
+}
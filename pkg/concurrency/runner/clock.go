@@ -0,0 +1,50 @@
+package runner
+
+import (
+	"time"
+)
+
+// Clock provides the current time and timers. It allows time-dependent functionality, such as a
+// [Scheduler], to be driven by a fake clock in tests.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTimer returns a new [Timer] that fires once after at least the given duration.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single-use timer created by a [Clock].
+type Timer interface {
+	// C returns the channel on which the current time is delivered when the timer fires.
+	C() <-chan time.Time
+	// Stop prevents the timer from firing. It returns false if the timer has already fired or been
+	// stopped.
+	Stop() bool
+}
+
+// systemClock is a [Clock] backed by the [time] package.
+type systemClock struct{}
+
+// Now returns the current time via [time.Now].
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// NewTimer returns a new [Timer] backed by [time.NewTimer].
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{t: time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	t *time.Timer
+}
+
+// C returns the underlying timer's channel.
+func (t systemTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+// Stop stops the underlying timer.
+func (t systemTimer) Stop() bool {
+	return t.t.Stop()
+}
@@ -1,10 +1,32 @@
 package runner
 
+import (
+	"time"
+)
+
 type options struct {
 	// CancelOnFailure indicates whether the Runner should cancel its context when a task fails.
 	CancelOnFailure bool
+	// CircuitBreaker indicates whether the Runner should use a circuit breaker.
+	CircuitBreaker bool
+	// CircuitBreakerCooldown is how long the circuit breaker stays open before probing.
+	CircuitBreakerCooldown time.Duration
+	// CircuitBreakerThreshold is the failure ratio at which the circuit breaker opens.
+	CircuitBreakerThreshold float64
+	// CircuitBreakerWindow is the number of recent task results over which the failure ratio is
+	// computed.
+	CircuitBreakerWindow uint
+	// CircuitStateCallback is called whenever the circuit breaker changes state.
+	CircuitStateCallback func(from, to CircuitState)
+	// Clock is the source of time for time-dependent functionality. If nil, the system clock is
+	// used.
+	Clock Clock
 	// Limit is the maximum number of goroutines that may run simultaneously.
 	Limit uint
+	// ProgressCallback is called with the Runner's progress as tasks finish.
+	ProgressCallback func(Progress)
+	// ProgressInterval is the minimum time between calls to ProgressCallback.
+	ProgressInterval time.Duration
 }
 
 // Option allows specifying a configuration option when creating a new Runner.
@@ -18,6 +40,61 @@ func WithCancelOnFailure() Option {
 	}
 }
 
+// WithCircuitBreaker is an option that makes the Runner stop invoking task functions once tasks
+// start failing at a high rate, protecting a failing dependency from being hammered.
+//
+// Once the ratio of failed tasks among the most recent results (see [WithCircuitBreakerWindow])
+// reaches threshold, the circuit opens: subsequent tasks fail fast with [ErrCircuitOpen] without
+// being run. After cooldown has elapsed, the circuit becomes half-open and a single probe task is
+// run; if it succeeds, the circuit closes and tasks run normally again, otherwise it reopens for
+// another cooldown. While the probe is running, other tasks fail with [ErrCircuitOpen].
+//
+// Tasks skipped because the Runner's context is done do not count towards the failure ratio.
+//
+// WithCircuitBreaker panics if threshold is not in (0, 1] or cooldown is negative.
+func WithCircuitBreaker(threshold float64, cooldown time.Duration) Option {
+	if !(threshold > 0 && threshold <= 1) {
+		panic("runner: threshold outside (0, 1] for WithCircuitBreaker")
+	}
+	if cooldown < 0 {
+		panic("runner: negative cooldown for WithCircuitBreaker")
+	}
+	return func(o *options) {
+		o.CircuitBreaker = true
+		o.CircuitBreakerThreshold = threshold
+		o.CircuitBreakerCooldown = cooldown
+	}
+}
+
+// WithCircuitBreakerWindow is an option that sets the number of most recent task results over
+// which the failure ratio of [WithCircuitBreaker] is computed. The circuit never opens before this
+// many tasks have completed. The default is 10.
+//
+// Specifying a window of 0 is equivalent to not specifying a window.
+func WithCircuitBreakerWindow(window uint) Option {
+	return func(o *options) {
+		o.CircuitBreakerWindow = window
+	}
+}
+
+// WithCircuitStateCallback is an option that sets a function to be called whenever the circuit
+// breaker of [WithCircuitBreaker] changes state. The callback is called synchronously from the
+// goroutine of the task that caused the transition, so it should return quickly.
+func WithCircuitStateCallback(callback func(from, to CircuitState)) Option {
+	return func(o *options) {
+		o.CircuitStateCallback = callback
+	}
+}
+
+// WithClock is an option that sets the [Clock] used by time-dependent functionality, such as the
+// ticks of a [Scheduler]. This is primarily useful for driving such functionality with a fake
+// clock in tests.
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.Clock = clock
+	}
+}
+
 // WithLimit is an option that allows setting the maximum number of goroutines that may run
 // simultaneously.
 //
@@ -28,3 +105,18 @@ func WithLimit(limit uint) Option {
 	}
 }
 
+// WithProgressCallback is an option that makes the Runner report its [Progress] to the given
+// callback as tasks finish, which allows showing progress for long batches of tasks without
+// wrapping every task function.
+//
+// To keep reporting cheap for large batches, the callback is called at most once per minInterval,
+// except that it is always called when a task finishes and no other submitted tasks remain
+// unfinished. Calls are serialized and made synchronously from the goroutine of the task that
+// finished, before [Runner.Wait] can observe that task as done, so the callback should return
+// quickly.
+func WithProgressCallback(minInterval time.Duration, callback func(Progress)) Option {
+	return func(o *options) {
+		o.ProgressInterval = minInterval
+		o.ProgressCallback = callback
+	}
+}
@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Progress is a snapshot of how far along a Runner's tasks are. See [WithProgressCallback].
+type Progress struct {
+	// Completed is the number of tasks that have finished, including failed and skipped tasks.
+	Completed int
+	// Failed is the number of finished tasks that returned an error or were skipped.
+	Failed int
+	// Total is the number of tasks submitted via [Runner.Go] so far.
+	Total int
+	// Elapsed is the time since the Runner was created.
+	Elapsed time.Duration
+	// ETA is the estimated time until all submitted tasks have finished, based on the average rate
+	// at which tasks have finished so far. It is 0 if no tasks have finished yet.
+	ETA time.Duration
+}
+
+// progressReporter tracks task counts and invokes a progress callback at a limited rate.
+type progressReporter struct {
+	clock       Clock
+	start       time.Time
+	minInterval time.Duration
+	callback    func(Progress)
+
+	total     atomic.Int64
+	completed atomic.Int64
+	failed    atomic.Int64
+
+	// emitMutex serializes calls to the callback, so that snapshots are delivered in order.
+	emitMutex sync.Mutex
+	lastEmit  time.Time
+	emitted   bool
+}
+
+func newProgressReporter(o *options, clock Clock) *progressReporter {
+	return &progressReporter{
+		clock:       clock,
+		start:       clock.Now(),
+		minInterval: o.ProgressInterval,
+		callback:    o.ProgressCallback,
+	}
+}
+
+// TaskAdded records that a task was submitted.
+func (p *progressReporter) TaskAdded() {
+	p.total.Add(1)
+}
+
+// TaskDone records that a task finished, and reports progress if the minimum interval has elapsed
+// since the last report or if all submitted tasks have finished.
+func (p *progressReporter) TaskDone(failed bool) {
+	if failed {
+		p.failed.Add(1)
+	}
+	completed := p.completed.Add(1)
+	final := completed == p.total.Load()
+
+	p.emitMutex.Lock()
+	defer p.emitMutex.Unlock()
+
+	now := p.clock.Now()
+	if !final && p.emitted && now.Sub(p.lastEmit) < p.minInterval {
+		return
+	}
+	p.emitted = true
+	p.lastEmit = now
+	p.callback(p.snapshot(now))
+}
+
+// snapshot returns the current progress as of the given time.
+func (p *progressReporter) snapshot(now time.Time) Progress {
+	prog := Progress{
+		Completed: int(p.completed.Load()),
+		Failed:    int(p.failed.Load()),
+		Total:     int(p.total.Load()),
+		Elapsed:   now.Sub(p.start),
+	}
+	if prog.Completed > 0 && prog.Total > prog.Completed {
+		perTask := prog.Elapsed / time.Duration(prog.Completed)
+		prog.ETA = perTask * time.Duration(prog.Total-prog.Completed)
+	}
+	return prog
+}
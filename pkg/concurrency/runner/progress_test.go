@@ -0,0 +1,120 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// progressRecorder records reported progress.
+type progressRecorder struct {
+	mutex   sync.Mutex
+	reports []Progress
+}
+
+func (p *progressRecorder) Record(prog Progress) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.reports = append(p.reports, prog)
+}
+
+func (p *progressRecorder) Len() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return len(p.reports)
+}
+
+func TestProgressReportsCountsAndETA(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	recorder := &progressRecorder{}
+	r := New(context.Background(), WithClock(clock), WithProgressCallback(0, recorder.Record))
+
+	release := make(chan struct{})
+	for range 3 {
+		r.Go(func() error {
+			<-release
+			return nil
+		})
+	}
+	r.Go(func() error {
+		return errors.New("task failed")
+	})
+	waitFor(t, func() bool {
+		return recorder.Len() == 1
+	})
+	clock.Advance(10 * time.Second)
+	close(release)
+	_ = r.Wait()
+
+	if got := len(recorder.reports); got != 4 {
+		t.Fatalf("got %d progress reports, want 4", got)
+	}
+	first := recorder.reports[0]
+	if want := (Progress{Completed: 1, Failed: 1, Total: 4}); first != want {
+		t.Errorf("first report got %+v, want %+v", first, want)
+	}
+	last := recorder.reports[3]
+	if want := (Progress{Completed: 4, Failed: 1, Total: 4, Elapsed: 10 * time.Second}); last != want {
+		t.Errorf("last report got %+v, want %+v", last, want)
+	}
+}
+
+func TestProgressETA(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	p := newProgressReporter(&options{ProgressCallback: func(Progress) {}}, clock)
+	for range 4 {
+		p.TaskAdded()
+	}
+	clock.Advance(10 * time.Second)
+	p.TaskDone(false)
+
+	got := p.snapshot(clock.Now())
+	want := Progress{Completed: 1, Total: 4, Elapsed: 10 * time.Second, ETA: 30 * time.Second}
+	if got != want {
+		t.Errorf("snapshot() got %+v, want %+v", got, want)
+	}
+}
+
+func TestProgressRateLimited(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	recorder := &progressRecorder{}
+	r := New(context.Background(), WithClock(clock), WithProgressCallback(time.Second, recorder.Record))
+
+	// Keep one task running so that no report is forced by all tasks having finished.
+	release := make(chan struct{})
+	r.Go(func() error {
+		<-release
+		return nil
+	})
+	for range 10 {
+		r.Go(func() error {
+			return nil
+		})
+	}
+	waitFor(t, func() bool {
+		return r.progress.completed.Load() == 10 && recorder.Len() > 0
+	})
+	if got := recorder.Len(); got != 1 {
+		t.Errorf("got %d progress reports within the minimum interval, want 1", got)
+	}
+
+	clock.Advance(time.Second)
+	close(release)
+	_ = r.Wait()
+	if got := len(recorder.reports); got != 2 {
+		t.Errorf("got %d progress reports, want 2", got)
+	}
+	if got := recorder.reports[len(recorder.reports)-1].Completed; got != 11 {
+		t.Errorf("final report got %d completed tasks, want 11", got)
+	}
+}
@@ -2,6 +2,9 @@
 // simultaneous goroutines. When the max is reached, attempting to run a new goroutine will block
 // until the number of running goroutines drops below the max. It also provides a way to aggregate
 // the errors returned from functions executed in each goroutine.
+//
+// Higher-level utilities built on top of a [Runner] are also provided: a [Batcher] for grouping
+// items into batches, and a [Scheduler] for running jobs periodically.
 package runner
 
 import (
@@ -30,6 +33,18 @@ func (c *cancelOnFailure) Cancel(err error) {
 	})
 }
 
+// Interface is the set of methods used to run tasks via a [Runner]. Code that accepts an Interface
+// rather than a *Runner can be tested deterministically with the runnertest package.
+type Interface interface {
+	// Go runs the given function as a task. See [Runner.Go].
+	Go(f func() error)
+	// Wait blocks until all tasks have finished and returns their errors. See [Runner.Wait].
+	Wait() []error
+}
+
+// Verify interface compliance:
+var _ Interface = (*Runner)(nil)
+
 // Runner allows running multiple goroutines with built-in WaitGroup management and error
 // accumulation.
 //
@@ -41,8 +56,17 @@ func (c *cancelOnFailure) Cancel(err error) {
 // manage cancellation of the Runner's tasks. Upon receiving the first non-nil error from a task:
 //   - The context is canceled, using the first encountered error as the cancellation reason.
 //   - The Runner will avoid running tasks in subsequent calls to [Runner.Go].
+//
+// If the [WithCircuitBreaker] option is provided, tasks fail fast with [ErrCircuitOpen] instead of
+// being run while too many recent tasks have failed.
+//
+// If the [WithProgressCallback] option is provided, the Runner's [Progress] is reported as tasks
+// finish.
 type Runner struct {
 	ctx          context.Context
+	clock        Clock
+	breaker      *circuitBreaker
+	progress     *progressReporter
 	failCanceler cancelOnFailure
 	wg           sync.WaitGroup
 	errs         syncErrorSlice
@@ -58,9 +82,19 @@ func New(ctx context.Context, opts ...Option) *Runner {
 	for _, opt := range opts {
 		opt(&ro)
 	}
+	r.clock = ro.Clock
+	if r.clock == nil {
+		r.clock = systemClock{}
+	}
 	if ro.Limit > 0 {
 		r.sem = make(chan struct{}, ro.Limit)
 	}
+	if ro.ProgressCallback != nil {
+		r.progress = newProgressReporter(&ro, r.clock)
+	}
+	if ro.CircuitBreaker {
+		r.breaker = newCircuitBreaker(&ro, r.clock)
+	}
 	if ro.CancelOnFailure {
 		r.ctx, r.failCanceler.cancel = context.WithCancelCause(ctx)
 	}
@@ -93,6 +127,15 @@ func (r *Runner) maybeSemDec() {
 //
 // Go should not be used in a nested manner, i.e. nesting a Go call within another Go call.
 func (r *Runner) Go(f func() error) {
+	r.goTask(f, nil)
+}
+
+// goTask implements [Runner.Go]. If onSkip is non-nil, it is called with the skip reason when f is
+// not run because the Runner's context is done.
+func (r *Runner) goTask(f func() error, onSkip func(cause error)) {
+	if r.progress != nil {
+		r.progress.TaskAdded()
+	}
 	r.maybeSemInc()
 	r.wg.Add(1)
 	var result error
@@ -108,6 +151,9 @@ func (r *Runner) Go(f func() error) {
 					r.failCanceler.Cancel(result)
 				}
 			}
+			if r.progress != nil {
+				r.progress.TaskDone(result != nil)
+			}
 			r.wg.Done()
 			r.maybeSemDec()
 		}()
@@ -115,12 +161,29 @@ func (r *Runner) Go(f func() error) {
 		// If a context was provided and it's now done, don't run the function.
 		if r.ctx.Err() != nil {
 			result = causeForTaskSkip(r.ctx)
+			if onSkip != nil {
+				onSkip(result)
+			}
 			return
 		}
-		result = f()
+		result = r.invoke(f)
 	}()
 }
 
+// invoke calls the task function, unless the circuit breaker is open.
+func (r *Runner) invoke(f func() error) error {
+	if r.breaker == nil {
+		return f()
+	}
+	allowed, probe := r.breaker.Allow()
+	if !allowed {
+		return ErrCircuitOpen
+	}
+	err := f()
+	r.breaker.Record(err != nil, probe)
+	return err
+}
+
 // Wait blocks until all function calls from the Go method have returned, then returns all the
 // errors from all goroutines.
 func (r *Runner) Wait() []error {
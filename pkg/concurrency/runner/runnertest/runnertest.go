@@ -0,0 +1,223 @@
+// Package runnertest provides a controllable implementation of [runner.Interface] for testing code
+// that runs tasks via a [runner.Runner].
+//
+// Unlike a real Runner, the [Runner] in this package never starts goroutines on its own. Tasks
+// passed to [Runner.Go] are queued, and the test decides when and in which order they run, either
+// one at a time via [Runner.Step] or concurrently via [Runner.Start]. This makes task ordering
+// deterministic, allows injecting failures, and allows asserting on the maximum number of tasks
+// observed running at once.
+package runnertest
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/mhoug89/hogo/pkg/concurrency/runner"
+)
+
+type options struct {
+	// Manual indicates whether Wait should leave running pending tasks to the test.
+	Manual bool
+}
+
+// Option allows specifying a configuration option when creating a new Runner.
+type Option func(*options)
+
+// WithManualStepping is an option that makes [Runner.Wait] block until the test has run every
+// submitted task, rather than running pending tasks itself. This is useful when the code under test
+// runs in its own goroutine and the test drives each task via [Runner.Step] or [Runner.Start].
+func WithManualStepping() Option {
+	return func(o *options) {
+		o.Manual = true
+	}
+}
+
+// Task is a handle to a task started via [Runner.Start].
+type Task struct {
+	done chan struct{}
+	err  error
+}
+
+// Done returns a channel that is closed when the task finishes.
+func (t *Task) Done() <-chan struct{} {
+	return t.done
+}
+
+// Err returns the error returned by the task. It must only be called after the channel returned by
+// [Task.Done] is closed.
+func (t *Task) Err() error {
+	return t.err
+}
+
+// pendingTask is a task that has been submitted but not yet started.
+type pendingTask struct {
+	f     func() error
+	index int
+}
+
+// Runner is a [runner.Interface] whose tasks only run when the test asks them to. This struct
+// should not be directly instantiated; callers should use the [New] function instead.
+type Runner struct {
+	opts options
+
+	mutex sync.Mutex
+	// cond is broadcast whenever a task is submitted or finishes.
+	cond       *sync.Cond
+	pending    []pendingTask
+	submitted  int
+	finished   int
+	running    int
+	maxRunning int
+	failures   map[int]error
+	errs       []error
+}
+
+// Verify interface compliance:
+var _ runner.Interface = (*Runner)(nil)
+
+// New returns a new Runner using the provided options.
+func New(opts ...Option) *Runner {
+	r := &Runner{failures: make(map[int]error)}
+	r.cond = sync.NewCond(&r.mutex)
+	for _, opt := range opts {
+		opt(&r.opts)
+	}
+	return r
+}
+
+// Go queues the given function to be run later by the test. It never blocks.
+func (r *Runner) Go(f func() error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.pending = append(r.pending, pendingTask{f: f, index: r.submitted})
+	r.submitted++
+	r.cond.Broadcast()
+}
+
+// Wait blocks until all submitted tasks have finished, then returns the errors from all of them in
+// the order in which they finished.
+//
+// Unless the [WithManualStepping] option was provided, Wait first runs all pending tasks one at a
+// time, in the order in which they were submitted.
+func (r *Runner) Wait() []error {
+	if !r.opts.Manual {
+		for r.Step() {
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for r.finished < r.submitted {
+		r.cond.Wait()
+	}
+	return slices.Clone(r.errs)
+}
+
+// FailTask makes the task with the given zero-based submission index return err instead of
+// invoking its function. It may be called before or after the task is submitted, but has no effect
+// once the task has started.
+func (r *Runner) FailTask(index int, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.failures[index] = err
+}
+
+// Pending returns the number of tasks that have been submitted but not yet started.
+func (r *Runner) Pending() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return len(r.pending)
+}
+
+// WaitForPending blocks until at least n tasks are pending. This allows a test to synchronize with
+// code under test that submits tasks from another goroutine.
+func (r *Runner) WaitForPending(n int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for len(r.pending) < n {
+		r.cond.Wait()
+	}
+}
+
+// MaxConcurrency returns the maximum number of tasks that have been observed running at the same
+// time.
+func (r *Runner) MaxConcurrency() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.maxRunning
+}
+
+// Step runs the oldest pending task to completion in the calling goroutine. It returns false if
+// there were no pending tasks.
+func (r *Runner) Step() bool {
+	return r.StepAt(0)
+}
+
+// StepAt runs the pending task at position i of the pending queue (0 being the oldest) to
+// completion in the calling goroutine, allowing tests to run tasks out of submission order. It
+// returns false if there is no such task.
+func (r *Runner) StepAt(i int) bool {
+	t, ok := r.take(i)
+	if !ok {
+		return false
+	}
+	r.run(t)
+	return true
+}
+
+// Start runs the oldest pending task in a new goroutine and returns a handle to it. It returns nil
+// if there were no pending tasks. Starting several tasks before they finish allows testing code
+// that depends on tasks running concurrently.
+func (r *Runner) Start() *Task {
+	t, ok := r.take(0)
+	if !ok {
+		return nil
+	}
+	handle := &Task{done: make(chan struct{})}
+	go func() {
+		handle.err = r.run(t)
+		close(handle.done)
+	}()
+	return handle
+}
+
+// take removes the pending task at position i and marks it as running.
+func (r *Runner) take(i int) (pendingTask, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if i < 0 || i >= len(r.pending) {
+		return pendingTask{}, false
+	}
+	t := r.pending[i]
+	r.pending = slices.Delete(r.pending, i, i+1)
+	r.running++
+	r.maxRunning = max(r.maxRunning, r.running)
+	return t, true
+}
+
+// run runs a task taken via take, recording its result.
+func (r *Runner) run(t pendingTask) error {
+	r.mutex.Lock()
+	err, fail := r.failures[t.index]
+	r.mutex.Unlock()
+
+	if !fail {
+		err = t.f()
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.running--
+	r.finished++
+	if err != nil {
+		r.errs = append(r.errs, err)
+	}
+	r.cond.Broadcast()
+	return err
+}
@@ -0,0 +1,142 @@
+package runnertest
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/mhoug89/hogo/pkg/concurrency/runner"
+)
+
+// submitN submits n tasks that record their index to order when run.
+func submitN(r runner.Interface, n int, order *[]int) {
+	for i := range n {
+		r.Go(func() error {
+			*order = append(*order, i)
+			return nil
+		})
+	}
+}
+
+func TestWaitRunsTasksInSubmissionOrder(t *testing.T) {
+	t.Parallel()
+
+	r := New()
+	var order []int
+	submitN(r, 5, &order)
+
+	if errs := r.Wait(); len(errs) != 0 {
+		t.Fatalf("Wait() returned errors %v, want none", errs)
+	}
+	if want := []int{0, 1, 2, 3, 4}; !slices.Equal(order, want) {
+		t.Errorf("tasks ran in order %v, want %v", order, want)
+	}
+	if got := r.MaxConcurrency(); got != 1 {
+		t.Errorf("MaxConcurrency() got %d, want 1", got)
+	}
+}
+
+func TestStepAndStepAt(t *testing.T) {
+	t.Parallel()
+
+	r := New()
+	var order []int
+	submitN(r, 4, &order)
+
+	if got := r.Pending(); got != 4 {
+		t.Fatalf("Pending() got %d, want 4", got)
+	}
+	if !r.StepAt(2) {
+		t.Fatalf("StepAt(2) returned false, want true")
+	}
+	if !r.Step() {
+		t.Fatalf("Step() returned false, want true")
+	}
+	if r.StepAt(5) {
+		t.Errorf("StepAt(5) with 2 pending tasks returned true, want false")
+	}
+	_ = r.Wait()
+	if r.Step() {
+		t.Errorf("Step() with no pending tasks returned true, want false")
+	}
+
+	if want := []int{2, 0, 1, 3}; !slices.Equal(order, want) {
+		t.Errorf("tasks ran in order %v, want %v", order, want)
+	}
+}
+
+func TestFailTask(t *testing.T) {
+	t.Parallel()
+
+	errInjected := errors.New("injected")
+	r := New()
+	r.FailTask(1, errInjected)
+	var order []int
+	submitN(r, 3, &order)
+
+	errs := r.Wait()
+	if len(errs) != 1 || !errors.Is(errs[0], errInjected) {
+		t.Errorf("Wait() got errors %v, want [%v]", errs, errInjected)
+	}
+	if want := []int{0, 2}; !slices.Equal(order, want) {
+		t.Errorf("tasks ran in order %v, want %v", order, want)
+	}
+}
+
+func TestStartTracksMaxConcurrency(t *testing.T) {
+	t.Parallel()
+
+	r := New()
+	release := make(chan struct{})
+	for range 3 {
+		r.Go(func() error {
+			<-release
+			return nil
+		})
+	}
+
+	var tasks []*Task
+	for range 3 {
+		tasks = append(tasks, r.Start())
+	}
+	if task := r.Start(); task != nil {
+		t.Errorf("Start() with no pending tasks returned %v, want nil", task)
+	}
+	close(release)
+	for _, task := range tasks {
+		<-task.Done()
+		if err := task.Err(); err != nil {
+			t.Errorf("task returned error %v, want nil", err)
+		}
+	}
+
+	if got := r.MaxConcurrency(); got != 3 {
+		t.Errorf("MaxConcurrency() got %d, want 3", got)
+	}
+}
+
+func TestManualStepping(t *testing.T) {
+	t.Parallel()
+
+	r := New(WithManualStepping())
+	waitDone := make(chan []error)
+	var order []int
+	go func() {
+		submitN(r, 2, &order)
+		waitDone <- r.Wait()
+	}()
+
+	r.WaitForPending(2)
+	select {
+	case <-waitDone:
+		t.Fatalf("Wait() returned before tasks were stepped")
+	default:
+	}
+	r.StepAt(1)
+	r.Step()
+	<-waitDone
+
+	if want := []int{1, 0}; !slices.Equal(order, want) {
+		t.Errorf("tasks ran in order %v, want %v", order, want)
+	}
+}
@@ -0,0 +1,184 @@
+package runner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule determines the times at which a [Scheduler] job runs.
+type Schedule interface {
+	// Next returns the first activation time strictly after the given time. A zero time indicates
+	// that there are no further activations.
+	Next(after time.Time) time.Time
+}
+
+// Every returns a [Schedule] that activates at a fixed interval. It panics if interval is not
+// positive.
+func Every(interval time.Duration) Schedule {
+	if interval <= 0 {
+		panic("runner: non-positive interval for Every")
+	}
+	return intervalSchedule(interval)
+}
+
+type intervalSchedule time.Duration
+
+// Next returns the given time plus the interval.
+func (s intervalSchedule) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(s))
+}
+
+// cronSearchLimit bounds how far into the future [cronSchedule.Next] searches for an activation,
+// so that expressions that can never match (e.g. February 30th) don't loop forever.
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// cronSchedule is a [Schedule] parsed from a cron expression. Each field is a bitmask of the
+// values for which the field matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar record whether the day-of-month and day-of-week fields start with "*",
+	// which affects how the two fields are combined.
+	domStar, dowStar bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = [5]cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 6},
+}
+
+// ParseCron parses a standard five-field cron expression ("minute hour day-of-month month
+// day-of-week") into a [Schedule]. Each field may be "*", a single value, a range ("a-b"), a step
+// over either of those ("*/n", "a-b/n"), or a comma-separated list of the above. Names of months
+// and weekdays are not supported; Sunday is day 0.
+//
+// As in most cron implementations, when both the day-of-month and day-of-week fields are
+// restricted, a day matches if either field matches. Like in Vixie cron, a field starting with "*",
+// such as "*/2", doesn't count as restricted, so e.g. "0 0 */2 * 1" only matches Mondays that fall
+// on odd days of the month.
+//
+// Activation times are computed in the location of the time passed to [Schedule.Next].
+func ParseCron(expr string) (Schedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression %q: got %d fields, want %d", expr, len(parts), len(cronFields))
+	}
+
+	var masks [5]uint64
+	for i, part := range parts {
+		mask, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", expr, err)
+		}
+		masks[i] = mask
+	}
+	return &cronSchedule{
+		minute:  masks[0],
+		hour:    masks[1],
+		dom:     masks[2],
+		month:   masks[3],
+		dow:     masks[4],
+		domStar: strings.HasPrefix(parts[2], "*"),
+		dowStar: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+// parseCronField parses a single comma-separated cron field into a bitmask.
+func parseCronField(field string, f cronField) (uint64, error) {
+	var mask uint64
+	for _, term := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(term, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepPart, f.name)
+			}
+		}
+
+		lo, hi := f.min, f.max
+		if rangePart != "*" {
+			loStr, hiStr, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = parseCronValue(loStr, f); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = parseCronValue(hiStr, f); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				// As in other cron implementations, "a/n" means "a-max/n".
+				hi = f.max
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q in %s field", rangePart, f.name)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+// parseCronValue parses a single numeric value of a cron field, checking that it's within bounds.
+func parseCronValue(s string, f cronField) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value %q in %s field; must be in [%d, %d]", s, f.name, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first time after the given time that matches the cron expression, or a zero
+// time if there is none within the next five years.
+func (s *cronSchedule) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.Add(cronSearchLimit)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether the day of the given time matches the day-of-month and day-of-week
+// fields.
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
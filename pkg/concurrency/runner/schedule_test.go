@@ -0,0 +1,143 @@
+package runner
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvery(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	s := Every(90 * time.Second)
+
+	got := s.Next(start)
+	want := start.Add(90 * time.Second)
+	if !got.Equal(want) {
+		t.Errorf("Next(%v) got %v, want %v", start, got, want)
+	}
+}
+
+func TestParseCronNext(t *testing.T) {
+	t.Parallel()
+
+	// 2026-01-01 is a Thursday.
+	start := time.Date(2026, time.January, 1, 10, 30, 15, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{
+			name: "every_minute",
+			expr: "* * * * *",
+			want: time.Date(2026, time.January, 1, 10, 31, 0, 0, time.UTC),
+		},
+		{
+			name: "step_minutes",
+			expr: "*/15 * * * *",
+			want: time.Date(2026, time.January, 1, 10, 45, 0, 0, time.UTC),
+		},
+		{
+			name: "fixed_time_later_today",
+			expr: "0 12 * * *",
+			want: time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "fixed_time_tomorrow",
+			expr: "0 9 * * *",
+			want: time.Date(2026, time.January, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "list_and_range",
+			expr: "5,10 8-9 * * *",
+			want: time.Date(2026, time.January, 2, 8, 5, 0, 0, time.UTC),
+		},
+		{
+			name: "day_of_week",
+			expr: "0 0 * * 1",
+			want: time.Date(2026, time.January, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "month_and_day",
+			expr: "0 0 15 3 *",
+			want: time.Date(2026, time.March, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "day_of_month_or_day_of_week",
+			expr: "0 0 10 * 6",
+			want: time.Date(2026, time.January, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// A day-of-month step starting with "*" is not a restriction, so both fields must match.
+			name: "day_of_month_step_and_day_of_week",
+			expr: "0 0 */2 * 1",
+			want: time.Date(2026, time.January, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "range_with_step",
+			expr: "0 1-10/4 * * *",
+			want: time.Date(2026, time.January, 2, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "never_matches",
+			expr: "0 0 30 2 *",
+			want: time.Time{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := ParseCron(tc.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) failed with error %v", tc.expr, err)
+			}
+			if got := s.Next(start); !got.Equal(tc.want) {
+				t.Errorf("Next(%v) got %v, want %v", start, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		expr string
+	}{
+		{
+			name: "too_few_fields",
+			expr: "* * * *",
+		},
+		{
+			name: "too_many_fields",
+			expr: "* * * * * *",
+		},
+		{
+			name: "value_out_of_range",
+			expr: "60 * * * *",
+		},
+		{
+			name: "not_a_number",
+			expr: "a * * * *",
+		},
+		{
+			name: "reversed_range",
+			expr: "* 10-5 * * *",
+		},
+		{
+			name: "zero_step",
+			expr: "*/0 * * * *",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := ParseCron(tc.expr); err == nil {
+				t.Errorf("ParseCron(%q) did not fail, but should have", tc.expr)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// ErrSchedulerStopped is returned when adding a job to a [Scheduler] that has been stopped.
+var ErrSchedulerStopped = errors.New("scheduler stopped")
+
+// OverlapPolicy determines what a [Scheduler] does when a job's tick arrives while a previous run
+// of the same job is still in progress.
+type OverlapPolicy int
+
+const (
+	// OverlapSkip drops the tick; the job next runs at the first tick after the current run ends.
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue runs the job again once the current run ends, once for every tick that arrived
+	// while it was running.
+	OverlapQueue
+	// OverlapConcurrent runs the job concurrently with any runs still in progress.
+	OverlapConcurrent
+)
+
+type jobOptions struct {
+	// Overlap is the policy applied when a tick arrives while the job is still running.
+	Overlap OverlapPolicy
+	// Jitter is the maximum random delay added to each tick.
+	Jitter time.Duration
+}
+
+// JobOption allows specifying a configuration option when adding a job to a [Scheduler].
+type JobOption func(*jobOptions)
+
+// WithOverlapPolicy is an option that sets the job's [OverlapPolicy]. The default is
+// [OverlapSkip].
+func WithOverlapPolicy(policy OverlapPolicy) JobOption {
+	return func(o *jobOptions) {
+		o.Overlap = policy
+	}
+}
+
+// WithJitter is an option that delays each of the job's ticks by a random duration in [0, max).
+// This helps avoid many jobs with the same schedule hitting a shared dependency at the same time.
+func WithJitter(max time.Duration) JobOption {
+	return func(o *jobOptions) {
+		o.Jitter = max
+	}
+}
+
+// Scheduler runs jobs periodically according to a [Schedule]. Each run of a job is executed as a
+// task of an underlying [Runner], so runs respect any options (such as [WithLimit]) passed to
+// [NewScheduler]. Time is read from the [Clock] set via [WithClock], which allows schedules to be
+// tested without sleeping.
+//
+// Once the context passed to [NewScheduler] is done, including when it is canceled due to
+// [WithCancelOnFailure], no further runs are scheduled; [Scheduler.Stop] must still be called to
+// collect the errors of the runs so far.
+//
+// Since the underlying Runner accumulates the errors of every failed run until [Scheduler.Stop]
+// is called, long-lived jobs that fail often should handle their own errors and return nil.
+//
+// This struct should not be directly instantiated; callers should use the [NewScheduler] function
+// instead.
+type Scheduler struct {
+	runner *Runner
+
+	mutex   sync.Mutex
+	stopped bool
+	stop    chan struct{}
+	// loops tracks the goroutines that wait for each job's ticks.
+	loops sync.WaitGroup
+}
+
+// NewScheduler returns a new Scheduler using the provided options to configure the underlying
+// [Runner].
+func NewScheduler(ctx context.Context, opts ...Option) *Scheduler {
+	return &Scheduler{
+		runner: New(ctx, opts...),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Add registers a job that runs fn at each activation of the given schedule, starting from the
+// first activation after the current time. If the Scheduler has been stopped, ErrSchedulerStopped
+// is returned.
+func (s *Scheduler) Add(schedule Schedule, fn func() error, opts ...JobOption) error {
+	j := &job{
+		scheduler: s,
+		schedule:  schedule,
+		fn:        fn,
+	}
+	for _, opt := range opts {
+		opt(&j.opts)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.stopped {
+		return ErrSchedulerStopped
+	}
+	s.loops.Add(1)
+	go j.loop()
+	return nil
+}
+
+// Stop stops scheduling new runs and waits for runs that are in progress or queued to finish,
+// then returns all the errors from all runs, as [Runner.Wait] does.
+//
+// If ctx is done before the runs finish, Stop returns ctx.Err() without the run errors; the runs
+// continue in the background, and Stop may be called again to keep waiting for them.
+func (s *Scheduler) Stop(ctx context.Context) ([]error, error) {
+	s.mutex.Lock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.stop)
+	}
+	s.mutex.Unlock()
+
+	done := make(chan []error, 1)
+	go func() {
+		s.loops.Wait()
+		done <- s.runner.Wait()
+	}()
+	select {
+	case errs := <-done:
+		return errs, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// job is a function registered with a [Scheduler], along with its schedule and run state.
+type job struct {
+	scheduler *Scheduler
+	schedule  Schedule
+	fn        func() error
+	opts      jobOptions
+
+	mutex   sync.Mutex
+	running bool
+	// queued is the number of ticks that arrived while the job was running, for OverlapQueue.
+	queued int
+}
+
+// loop waits for each of the job's ticks and runs the job, until the Scheduler is stopped, the
+// context of its Runner is done, or the schedule has no further activations.
+func (j *job) loop() {
+	defer j.scheduler.loops.Done()
+
+	ctx := j.scheduler.runner.ctx
+	clock := j.scheduler.runner.clock
+	next := j.schedule.Next(clock.Now())
+	for !next.IsZero() {
+		delay := next.Sub(clock.Now())
+		if j.opts.Jitter > 0 {
+			delay += rand.N(j.opts.Jitter)
+		}
+		timer := clock.NewTimer(delay)
+		select {
+		case <-j.scheduler.stop:
+			timer.Stop()
+			return
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+		// The context may have been done at the same time as the tick, in which case every run
+		// would be skipped with an error.
+		if ctx.Err() != nil {
+			return
+		}
+		j.fire()
+
+		// Skip any activations that passed while the tick was being handled, rather than firing
+		// them all in a burst.
+		now := clock.Now()
+		next = j.schedule.Next(next)
+		for !next.IsZero() && next.Before(now) {
+			next = j.schedule.Next(next)
+		}
+	}
+}
+
+// fire runs the job for a single tick according to its overlap policy.
+func (j *job) fire() {
+	r := j.scheduler.runner
+	switch j.opts.Overlap {
+	case OverlapConcurrent:
+		r.Go(j.fn)
+	case OverlapQueue:
+		j.mutex.Lock()
+		if j.running {
+			j.queued++
+			j.mutex.Unlock()
+			return
+		}
+		j.running = true
+		j.mutex.Unlock()
+		r.goTask(j.runQueued, j.reset)
+	default:
+		j.mutex.Lock()
+		if j.running {
+			j.mutex.Unlock()
+			return
+		}
+		j.running = true
+		j.mutex.Unlock()
+		r.goTask(func() error {
+			defer j.reset(nil)
+			return j.fn()
+		}, j.reset)
+	}
+}
+
+// runQueued runs the job repeatedly until no more ticks are queued, returning the errors of all
+// the runs.
+func (j *job) runQueued() error {
+	var errs []error
+	for {
+		if err := j.fn(); err != nil {
+			errs = append(errs, err)
+		}
+
+		j.mutex.Lock()
+		if j.queued == 0 {
+			j.running = false
+			j.mutex.Unlock()
+			return errors.Join(errs...)
+		}
+		j.queued--
+		j.mutex.Unlock()
+	}
+}
+
+// reset marks the job as no longer running and drops any queued ticks. Its signature allows it to
+// be used as a skip callback for [Runner.goTask].
+func (j *job) reset(error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.running = false
+	j.queued = 0
+}
@@ -0,0 +1,403 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock is a [Clock] whose time only moves when Advance is called.
+type fakeClock struct {
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*fakeTimer
+}
+
+func newFakeClock() *fakeClock {
+	c := &fakeClock{now: time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	c.cond = sync.NewCond(&c.mutex)
+	return c
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	t := &fakeTimer{clock: c, deadline: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.timers = append(c.timers, t)
+	c.cond.Broadcast()
+	return t
+}
+
+// Advance moves the clock forward, firing any timers whose deadline has been reached.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = c.now.Add(d)
+	remaining := c.timers[:0]
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			remaining = append(remaining, t)
+			continue
+		}
+		t.c <- c.now
+	}
+	c.timers = remaining
+}
+
+// WaitForTimers blocks until at least n timers are pending.
+func (c *fakeClock) WaitForTimers(n int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+type fakeTimer struct {
+	clock    *fakeClock
+	deadline time.Time
+	c        chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+
+	for i, other := range t.clock.timers {
+		if other == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// tick advances the clock by d and waits for the scheduler's single job to fire and start waiting
+// for its next tick.
+func tick(clock *fakeClock, d time.Duration) {
+	clock.WaitForTimers(1)
+	clock.Advance(d)
+	clock.WaitForTimers(1)
+}
+
+// waitFor polls until cond returns true, failing the test if it doesn't within a few seconds.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met before deadline")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSchedulerRunsJobEveryInterval(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	s := NewScheduler(context.Background(), WithClock(clock))
+	runs := make(chan struct{}, 8)
+	if err := s.Add(Every(time.Minute), func() error {
+		runs <- struct{}{}
+		return nil
+	}); err != nil {
+		t.Fatalf("Add() failed with error %v", err)
+	}
+
+	for i := range 3 {
+		clock.WaitForTimers(1)
+		clock.Advance(time.Minute)
+		select {
+		case <-runs:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("job did not run after tick %d", i)
+		}
+	}
+
+	errs, err := s.Stop(context.Background())
+	if err != nil {
+		t.Fatalf("Stop() failed with error %v", err)
+	}
+	if len(errs) != 0 {
+		t.Errorf("Stop() returned errors %v, want none", messages(errs))
+	}
+}
+
+func TestSchedulerDoesNotRunBeforeInterval(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	s := NewScheduler(context.Background(), WithClock(clock))
+	runCount := atomic.Int32{}
+	_ = s.Add(Every(time.Hour), func() error {
+		runCount.Add(1)
+		return nil
+	})
+
+	clock.WaitForTimers(1)
+	clock.Advance(59 * time.Minute)
+	_, _ = s.Stop(context.Background())
+
+	if got := runCount.Load(); got != 0 {
+		t.Errorf("job ran %d times, want 0", got)
+	}
+}
+
+func TestSchedulerOverlapPolicies(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name           string
+		policy         OverlapPolicy
+		wantRunCount   int32
+		wantMaxRunning int32
+	}{
+		{
+			name:           "skip",
+			policy:         OverlapSkip,
+			wantRunCount:   1,
+			wantMaxRunning: 1,
+		},
+		{
+			name:           "queue",
+			policy:         OverlapQueue,
+			wantRunCount:   3,
+			wantMaxRunning: 1,
+		},
+		{
+			name:           "concurrent",
+			policy:         OverlapConcurrent,
+			wantRunCount:   3,
+			wantMaxRunning: 3,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			clock := newFakeClock()
+			s := NewScheduler(context.Background(), WithClock(clock))
+			release := make(chan struct{})
+			var runCount, running, maxRunning atomic.Int32
+			_ = s.Add(Every(time.Minute), func() error {
+				runCount.Add(1)
+				n := running.Add(1)
+				for {
+					cur := maxRunning.Load()
+					if n <= cur || maxRunning.CompareAndSwap(cur, n) {
+						break
+					}
+				}
+				<-release
+				running.Add(-1)
+				return nil
+			}, WithOverlapPolicy(tc.policy))
+
+			// Fire three ticks while the first run is blocked.
+			for range 3 {
+				tick(clock, time.Minute)
+			}
+			waitFor(t, func() bool {
+				return running.Load() == tc.wantMaxRunning
+			})
+			close(release)
+			if _, err := s.Stop(context.Background()); err != nil {
+				t.Fatalf("Stop() failed with error %v", err)
+			}
+
+			if got := runCount.Load(); got != tc.wantRunCount {
+				t.Errorf("job ran %d times, want %d", got, tc.wantRunCount)
+			}
+			if got := maxRunning.Load(); got != tc.wantMaxRunning {
+				t.Errorf("observed %d concurrent runs, want %d", got, tc.wantMaxRunning)
+			}
+		})
+	}
+}
+
+func TestSchedulerStopReturnsJobErrors(t *testing.T) {
+	t.Parallel()
+
+	errJob := errors.New("job failed")
+	clock := newFakeClock()
+	s := NewScheduler(context.Background(), WithClock(clock))
+	// Run concurrently so that the second tick can't be skipped due to the first run being slow.
+	_ = s.Add(Every(time.Minute), func() error {
+		return errJob
+	}, WithOverlapPolicy(OverlapConcurrent))
+
+	tick(clock, time.Minute)
+	tick(clock, time.Minute)
+	errs, err := s.Stop(context.Background())
+	if err != nil {
+		t.Fatalf("Stop() failed with error %v", err)
+	}
+
+	if len(errs) != 2 {
+		t.Fatalf("Stop() returned %d errors, want 2; errs: %v", len(errs), messages(errs))
+	}
+	for _, err := range errs {
+		if !errors.Is(err, errJob) {
+			t.Errorf("Stop() returned error %v, want %v", err, errJob)
+		}
+	}
+}
+
+func TestSchedulerStopRespectsContext(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	s := NewScheduler(context.Background(), WithClock(clock))
+	release := make(chan struct{})
+	_ = s.Add(Every(time.Minute), func() error {
+		<-release
+		return nil
+	})
+	tick(clock, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.Stop(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Stop() with a running job got error %v, want %v", err, context.Canceled)
+	}
+
+	close(release)
+	if _, err := s.Stop(context.Background()); err != nil {
+		t.Errorf("Stop() after job finished got error %v, want nil", err)
+	}
+}
+
+func TestSchedulerStopsSchedulingWhenContextDone(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		// newScheduler returns a scheduler and a function that makes its context done.
+		newScheduler func(clock *fakeClock) (*Scheduler, func())
+		job          func() error
+	}{
+		{
+			name: "context_canceled",
+			newScheduler: func(clock *fakeClock) (*Scheduler, func()) {
+				ctx, cancel := context.WithCancel(context.Background())
+				return NewScheduler(ctx, WithClock(clock)), cancel
+			},
+			job: func() error {
+				return nil
+			},
+		},
+		{
+			name: "cancel_on_failure",
+			newScheduler: func(clock *fakeClock) (*Scheduler, func()) {
+				s := NewScheduler(context.Background(), WithClock(clock), WithCancelOnFailure())
+				return s, func() {}
+			},
+			job: func() error {
+				return errors.New("job failed")
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			clock := newFakeClock()
+			s, done := tc.newScheduler(clock)
+			var runs atomic.Int32
+			_ = s.Add(Every(time.Minute), func() error {
+				runs.Add(1)
+				return tc.job()
+			}, WithOverlapPolicy(OverlapConcurrent))
+
+			// With WithCancelOnFailure, the loop may exit right after this tick, so don't wait for
+			// it to schedule the next one as tick does.
+			clock.WaitForTimers(1)
+			clock.Advance(time.Minute)
+			waitFor(t, func() bool {
+				return runs.Load() == 1
+			})
+			done()
+			// The job's loop exits, dropping its pending timer.
+			waitFor(t, func() bool {
+				clock.mutex.Lock()
+				defer clock.mutex.Unlock()
+				return len(clock.timers) == 0
+			})
+			for range 50 {
+				clock.Advance(time.Minute)
+			}
+
+			errs, err := s.Stop(context.Background())
+			if err != nil {
+				t.Fatalf("Stop() failed with error %v", err)
+			}
+			if got := runs.Load(); got != 1 {
+				t.Errorf("job ran %d times, want %d", got, 1)
+			}
+			// Only the failure that canceled the context, if any, is reported.
+			if len(errs) > 1 {
+				t.Errorf("Stop() returned %d errors, want at most 1; errs: %v", len(errs), messages(errs))
+			}
+		})
+	}
+}
+
+func TestSchedulerAddAfterStop(t *testing.T) {
+	t.Parallel()
+
+	s := NewScheduler(context.Background(), WithClock(newFakeClock()))
+	_, _ = s.Stop(context.Background())
+
+	err := s.Add(Every(time.Minute), func() error {
+		return nil
+	})
+	if !errors.Is(err, ErrSchedulerStopped) {
+		t.Errorf("Add() after Stop() got error %v, want %v", err, ErrSchedulerStopped)
+	}
+}
+
+func TestSchedulerJitterDelaysTick(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	s := NewScheduler(context.Background(), WithClock(clock))
+	runs := make(chan struct{}, 1)
+	_ = s.Add(Every(time.Minute), func() error {
+		runs <- struct{}{}
+		return nil
+	}, WithJitter(time.Minute))
+
+	// The tick may never fire before the interval, and must fire before interval + jitter.
+	clock.WaitForTimers(1)
+	clock.Advance(time.Minute - time.Nanosecond)
+	select {
+	case <-runs:
+		t.Fatalf("job ran before its interval elapsed")
+	default:
+	}
+	clock.Advance(time.Minute)
+	select {
+	case <-runs:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("job did not run within interval + jitter")
+	}
+	_, _ = s.Stop(context.Background())
+}
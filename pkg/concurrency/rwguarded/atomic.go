@@ -0,0 +1,59 @@
+package rwguarded
+
+import (
+	"sync/atomic"
+)
+
+// Atomic is a copy-on-write alternative to [RWGuarded] for values that are read far more often
+// than they are written. Reads are a single atomic load, so unlike with RWGuarded, readers never
+// contend with each other; writes replace the whole value by atomically swapping a pointer to a
+// new copy of it.
+//
+// Because readers are not synchronized with writers at all, the value must be treated as
+// immutable once stored: writers must never modify data reachable from a stored value (e.g. the
+// contents of a map or slice) in place, but instead build a new value and store that.
+//
+// This struct should not be directly instantiated; callers should use the [NewAtomic] function
+// instead.
+type Atomic[V any] struct {
+	ptr atomic.Pointer[V]
+}
+
+// NewAtomic initializes and returns an [Atomic] of the provided type.
+func NewAtomic[V any](val V) *Atomic[V] {
+	a := &Atomic[V]{}
+	a.ptr.Store(&val)
+	return a
+}
+
+// Get returns a copy of the underlying value.
+func (a *Atomic[V]) Get() V {
+	return *a.ptr.Load()
+}
+
+// Set sets the underlying value.
+func (a *Atomic[V]) Set(val V) {
+	a.ptr.Store(&val)
+}
+
+// Update allows performing a read-modify-write transaction on the underlying value. The updater
+// function is passed a pointer to a copy of the current value, which it may change in place; the
+// copy then replaces the current value, unless another write happened in the meantime, in which
+// case the updater is called again with a copy of the newer value. If the updater returns an
+// error, the value is left unchanged and the error is returned from this method.
+//
+// Since the updater may be called multiple times, it should be free of side effects. As the copy
+// is shallow, the updater must replace, rather than modify in place, any reference-type data it
+// wants to change.
+func (a *Atomic[V]) Update(updater func(*V) error) error {
+	for {
+		cur := a.ptr.Load()
+		next := *cur
+		if err := updater(&next); err != nil {
+			return err
+		}
+		if a.ptr.CompareAndSwap(cur, &next) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package rwguarded
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestAtomicSetAndGet(t *testing.T) {
+	t.Parallel()
+
+	a := NewAtomic[string]("initial-value")
+	if got, want := a.Get(), "initial-value"; got != want {
+		t.Fatalf("Get() got %q, want %q", got, want)
+	}
+
+	a.Set("new-value")
+	if got, want := a.Get(), "new-value"; got != want {
+		t.Fatalf("Get() got %q, want %q", got, want)
+	}
+}
+
+func TestAtomicUpdate(t *testing.T) {
+	t.Parallel()
+
+	errUpdaterFailed := errors.New("updater failed")
+
+	for _, tc := range []struct {
+		name    string
+		updater func(val *int) error
+		wantVal int
+		wantErr error
+	}{
+		{
+			name: "update_ok",
+			updater: func(val *int) error {
+				*val++
+				return nil
+			},
+			wantVal: 2,
+			wantErr: nil,
+		},
+		{
+			name: "update_error",
+			updater: func(val *int) error {
+				*val = 100
+				return errUpdaterFailed
+			},
+			wantVal: 1,
+			wantErr: errUpdaterFailed,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := NewAtomic[int](1)
+			if err := a.Update(tc.updater); err != tc.wantErr {
+				t.Errorf("Update() got error %v, want %v", err, tc.wantErr)
+			}
+			if got := a.Get(); got != tc.wantVal {
+				t.Errorf("Get() got %d, want %d", got, tc.wantVal)
+			}
+		})
+	}
+}
+
+func TestAtomicConcurrentUpdatesAreNotLost(t *testing.T) {
+	t.Parallel()
+
+	a := NewAtomic[int](0)
+	wg := sync.WaitGroup{}
+	for range 16 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				_ = a.Update(func(val *int) error {
+					*val++
+					return nil
+				})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				_ = a.Get()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := a.Get(); got != 1600 {
+		t.Errorf("Get() got %d, want %d", got, 1600)
+	}
+}
+
+// benchConfig is a moderately sized value, representative of a configuration struct.
+type benchConfig struct {
+	Name     string
+	Limits   [8]int
+	Enabled  bool
+	Replicas int
+}
+
+// writeEvery is how many reads each benchmark goroutine performs per write, simulating a
+// read-mostly workload.
+const writeEvery = 10000
+
+func BenchmarkAtomicReadMostly(b *testing.B) {
+	a := NewAtomic(benchConfig{Name: "config"})
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			i++
+			if i%writeEvery == 0 {
+				_ = a.Update(func(c *benchConfig) error {
+					c.Replicas++
+					return nil
+				})
+				continue
+			}
+			_ = a.Get()
+		}
+	})
+}
+
+func BenchmarkRWGuardedReadMostly(b *testing.B) {
+	g := New(benchConfig{Name: "config"})
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			i++
+			if i%writeEvery == 0 {
+				_ = g.Update(func(c *benchConfig) error {
+					c.Replicas++
+					return nil
+				})
+				continue
+			}
+			_ = g.Get()
+		}
+	})
+}
+
+func BenchmarkAtomicReadOnly(b *testing.B) {
+	a := NewAtomic(benchConfig{Name: "config"})
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = a.Get()
+		}
+	})
+}
+
+func BenchmarkRWGuardedReadOnly(b *testing.B) {
+	g := New(benchConfig{Name: "config"})
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = g.Get()
+		}
+	})
+}
@@ -0,0 +1,213 @@
+package rwguarded
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides the current time. It allows time-dependent functionality, such as the expiry of
+// the entries of an [ExpiringMap], to be driven by a fake clock in tests. See [WithClock].
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+// systemClock is a [Clock] backed by the [time] package.
+type systemClock struct{}
+
+// Now implements [Clock].
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// expiringEntry is a value stored in an [ExpiringMap].
+type expiringEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// expiredAt returns whether the entry has expired at the provided time.
+func (e expiringEntry[V]) expiredAt(now time.Time) bool {
+	return !now.Before(e.expiresAt)
+}
+
+// ExpiringMap is like [Map], but each entry expires once its time-to-live (TTL) has elapsed.
+// Expired entries are invisible to all methods, and are reclaimed by a background janitor
+// goroutine, which is stopped via [ExpiringMap.Close]. This struct should not be directly
+// instantiated; callers should use the [NewExpiringMap] function instead.
+//
+// The janitor's schedule is always based on the system clock, even if the [WithClock] option is
+// provided. Tests using a fake clock should disable the janitor via [WithJanitorInterval] and call
+// [ExpiringMap.DeleteExpired] instead.
+type ExpiringMap[K comparable, V any] struct {
+	rwLock     *rwMutex
+	entryByKey map[K]expiringEntry[V]
+	ttl        time.Duration
+	clock      Clock
+	onEvict    func(key K, value V)
+
+	closeOnce sync.Once
+	stop      chan struct{}
+	// janitorDone is closed once the janitor has exited. It is nil if there is no janitor.
+	janitorDone chan struct{}
+}
+
+// NewExpiringMap initializes and returns an [ExpiringMap] of the provided types, whose entries
+// expire after the provided default TTL, using the provided options. It panics if ttl isn't
+// positive.
+//
+// If onEvict isn't nil, it is called with each entry reclaimed because it expired, after the lock
+// has been released. It is not called for entries that are deleted, overwritten, or cleared.
+func NewExpiringMap[K comparable, V any](ttl time.Duration, onEvict func(key K, value V), opts ...ExpiringMapOption) *ExpiringMap[K, V] {
+	if ttl <= 0 {
+		panic("rwguarded: ExpiringMap TTL must be positive")
+	}
+	o := expiringMapOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Clock == nil {
+		o.Clock = systemClock{}
+	}
+	m := &ExpiringMap[K, V]{
+		rwLock:     newRWMutex(false, buildMapOptions(o.Options)),
+		entryByKey: make(map[K]expiringEntry[V]),
+		ttl:        ttl,
+		clock:      o.Clock,
+		onEvict:    onEvict,
+		stop:       make(chan struct{}),
+	}
+	if !o.NoJanitor {
+		interval := o.JanitorInterval
+		if interval == 0 {
+			interval = ttl
+		}
+		m.janitorDone = make(chan struct{})
+		go m.runJanitor(interval)
+	}
+	return m
+}
+
+// runJanitor calls DeleteExpired at the provided interval until the map is closed.
+func (m *ExpiringMap[K, V]) runJanitor(interval time.Duration) {
+	defer close(m.janitorDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			m.DeleteExpired()
+		}
+	}
+}
+
+// Close stops the janitor, waiting for it to exit. The map remains usable after Close, but expired
+// entries are then only reclaimed via [ExpiringMap.DeleteExpired]. Calling Close more than once is
+// a no-op.
+func (m *ExpiringMap[K, V]) Close() {
+	m.closeOnce.Do(func() {
+		close(m.stop)
+	})
+	if m.janitorDone != nil {
+		<-m.janitorDone
+	}
+}
+
+// Clear deletes all entries.
+func (m *ExpiringMap[K, V]) Clear() {
+	m.rwLock.Lock("ExpiringMap.Clear")
+	defer m.rwLock.Unlock()
+
+	m.entryByKey = make(map[K]expiringEntry[V])
+}
+
+// Count returns the number of unexpired entries. Since expired entries must be skipped, this takes
+// time proportional to the total number of entries.
+func (m *ExpiringMap[K, V]) Count() int {
+	m.rwLock.RLock("ExpiringMap.Count")
+	defer m.rwLock.RUnlock()
+
+	now := m.clock.Now()
+	n := 0
+	for _, e := range m.entryByKey {
+		if !e.expiredAt(now) {
+			n++
+		}
+	}
+	return n
+}
+
+// Delete deletes the entries at the provided key(s).
+func (m *ExpiringMap[K, V]) Delete(keys ...K) {
+	m.rwLock.Lock("ExpiringMap.Delete")
+	defer m.rwLock.Unlock()
+
+	for _, k := range keys {
+		delete(m.entryByKey, k)
+	}
+}
+
+// DeleteExpired reclaims all expired entries, calling the eviction callback for each of them, and
+// returns the number of reclaimed entries. It is called periodically by the janitor, but may also
+// be called manually, e.g. when the janitor is disabled.
+func (m *ExpiringMap[K, V]) DeleteExpired() int {
+	type evicted struct {
+		key   K
+		value V
+	}
+	var evictions []evicted
+
+	m.rwLock.Lock("ExpiringMap.DeleteExpired")
+	now := m.clock.Now()
+	for k, e := range m.entryByKey {
+		if e.expiredAt(now) {
+			delete(m.entryByKey, k)
+			evictions = append(evictions, evicted{key: k, value: e.value})
+		}
+	}
+	m.rwLock.Unlock()
+
+	if m.onEvict != nil {
+		for _, e := range evictions {
+			m.onEvict(e.key, e.value)
+		}
+	}
+	return len(evictions)
+}
+
+// Load returns the value associated with the provided key. If the key did not exist or its entry
+// has expired, the boolean return value will be false.
+func (m *ExpiringMap[K, V]) Load(key K) (V, bool) {
+	m.rwLock.RLock("ExpiringMap.Load")
+	defer m.rwLock.RUnlock()
+
+	e, ok := m.entryByKey[key]
+	if !ok || e.expiredAt(m.clock.Now()) {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// Store stores the provided value at the provided key, expiring after the default TTL of the map.
+func (m *ExpiringMap[K, V]) Store(key K, value V) {
+	m.StoreWithTTL(key, value, m.ttl)
+}
+
+// StoreWithTTL stores the provided value at the provided key, expiring after the provided TTL
+// instead of the default TTL of the map.
+func (m *ExpiringMap[K, V]) StoreWithTTL(key K, value V, ttl time.Duration) {
+	m.rwLock.Lock("ExpiringMap.StoreWithTTL")
+	defer m.rwLock.Unlock()
+
+	m.entryByKey[key] = expiringEntry[V]{value: value, expiresAt: m.clock.Now().Add(ttl)}
+}
+
+// Stats returns the statistics recorded for the lock of this [ExpiringMap]. If the [WithStats]
+// option was not provided via [WithOptions], zero statistics are returned.
+func (m *ExpiringMap[K, V]) Stats() Stats {
+	return m.rwLock.stats.Snapshot()
+}
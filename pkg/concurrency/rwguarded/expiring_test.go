@@ -0,0 +1,153 @@
+package rwguarded
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a [Clock] whose time only changes when advanced.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// Now implements [Clock].
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func TestExpiringMapExpiry(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	rwgMap := NewExpiringMap[string, int](time.Minute, nil, WithClock(clock), WithJanitorInterval(0))
+	defer rwgMap.Close()
+
+	rwgMap.Store("default", 1)
+	rwgMap.StoreWithTTL("short", 2, time.Second)
+	rwgMap.StoreWithTTL("long", 3, time.Hour)
+
+	for _, step := range []struct {
+		advance   time.Duration
+		wantKeys  []string
+		wantCount int
+	}{
+		{advance: 0, wantKeys: []string{"default", "short", "long"}, wantCount: 3},
+		{advance: time.Second, wantKeys: []string{"default", "long"}, wantCount: 2},
+		{advance: time.Minute, wantKeys: []string{"long"}, wantCount: 1},
+		{advance: time.Hour, wantKeys: nil, wantCount: 0},
+	} {
+		clock.Advance(step.advance)
+		for _, k := range step.wantKeys {
+			if _, ok := rwgMap.Load(k); !ok {
+				t.Errorf("Load(%q) after advancing by %v did not find entry, but should have", k, step.advance)
+			}
+		}
+		if got := rwgMap.Count(); got != step.wantCount {
+			t.Errorf("Count() after advancing by %v got %d, want %d", step.advance, got, step.wantCount)
+		}
+	}
+	if _, ok := rwgMap.Load("short"); ok {
+		t.Errorf("Load(%q) found expired entry, but should not have", "short")
+	}
+}
+
+func TestExpiringMapDeleteExpiredCallsOnEvict(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	evicted := map[string]int{}
+	rwgMap := NewExpiringMap(time.Minute, func(k string, v int) {
+		evicted[k] = v
+	}, WithClock(clock), WithJanitorInterval(0))
+	defer rwgMap.Close()
+
+	rwgMap.Store("a", 1)
+	rwgMap.Store("b", 2)
+	rwgMap.StoreWithTTL("c", 3, time.Hour)
+	rwgMap.Store("deleted", 4)
+	rwgMap.Delete("deleted")
+
+	if got := rwgMap.DeleteExpired(); got != 0 {
+		t.Errorf("DeleteExpired() before expiry got %d, want %d", got, 0)
+	}
+	clock.Advance(time.Minute)
+	if got := rwgMap.DeleteExpired(); got != 2 {
+		t.Errorf("DeleteExpired() got %d, want %d", got, 2)
+	}
+	if len(evicted) != 2 || evicted["a"] != 1 || evicted["b"] != 2 {
+		t.Errorf("evicted entries got %v, want %v", evicted, map[string]int{"a": 1, "b": 2})
+	}
+	// Storing again after expiry revives the key.
+	rwgMap.Store("a", 5)
+	if got, ok := rwgMap.Load("a"); !ok || got != 5 {
+		t.Errorf("Load(%q) got (%d, %t), want (%d, %t)", "a", got, ok, 5, true)
+	}
+}
+
+func TestExpiringMapJanitorReclaimsEntries(t *testing.T) {
+	t.Parallel()
+
+	evicted := make(chan string, 1)
+	rwgMap := NewExpiringMap(time.Millisecond, func(k string, _ int) {
+		evicted <- k
+	})
+	defer rwgMap.Close()
+
+	rwgMap.Store("key", 1)
+	if got := receive(t, evicted); got != "key" {
+		t.Errorf("evicted key got %q, want %q", got, "key")
+	}
+}
+
+func TestExpiringMapCloseIsIdempotent(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewExpiringMap[string, int](time.Hour, nil)
+	rwgMap.Close()
+	rwgMap.Close()
+
+	// The map remains usable after Close.
+	rwgMap.Store("key", 1)
+	if got, ok := rwgMap.Load("key"); !ok || got != 1 {
+		t.Errorf("Load() after Close() got (%d, %t), want (%d, %t)", got, ok, 1, true)
+	}
+}
+
+func TestNewExpiringMapPanicsOnInvalidTTL(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("NewExpiringMap() with zero TTL did not panic, but should have")
+		}
+	}()
+	NewExpiringMap[string, int](0, nil)
+}
+
+func TestExpiringMapWithOptionsAppliesStats(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewExpiringMap[string, int](time.Minute, nil, WithJanitorInterval(0), WithOptions(WithStats()))
+	rwgMap.Store("key", 1)
+
+	if got, want := rwgMap.Stats().WriteAcquisitions, uint64(1); got != want {
+		t.Errorf("Stats().WriteAcquisitions got %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,71 @@
+package rwguarded
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNoHistory is returned from [RWGuarded.Rollback] when there is no previous value to restore.
+var ErrNoHistory = errors.New("no history")
+
+// HistoryEntry is a value retained by a [RWGuarded] created with the [WithHistory] option.
+type HistoryEntry[V any] struct {
+	// Value is a copy of the value, made the same way as by [RWGuarded.Get].
+	Value V
+	// Time is the time at which the value was written, or at which the [RWGuarded] was created for
+	// its initial value.
+	Time time.Time
+}
+
+// History returns the values retained by this [RWGuarded], oldest first. The last entry is always
+// the current value, preceded by up to the number of previous values passed to [WithHistory].
+// Values restored via [RWGuarded.Rollback] are not retained again, so they appear with the time at
+// which they were originally written. If the WithHistory option was not provided, nil is returned.
+func (g *RWGuarded[V]) History() []HistoryEntry[V] {
+	g.rwLock.RLock("RWGuarded.History")
+	defer g.rwLock.RUnlock()
+
+	if len(g.history) == 0 {
+		return nil
+	}
+	history := make([]HistoryEntry[V], len(g.history))
+	copy(history, g.history)
+	return history
+}
+
+// Rollback restores the previous value retained by this [RWGuarded] created with the
+// [WithHistory] option, discarding the current one. It returns ErrNoHistory if there is no
+// previous value, e.g. because all of them were already rolled back.
+//
+// Rolling back counts as a write: it increments the version and is reported to watchers. The
+// restored value is not validated again, since it was validated when originally written.
+func (g *RWGuarded[V]) Rollback() error {
+	g.rwLock.Lock("RWGuarded.Rollback")
+	defer g.rwLock.Unlock()
+
+	if len(g.history) < 2 {
+		return ErrNoHistory
+	}
+	old := g.value
+	g.history[len(g.history)-1] = HistoryEntry[V]{}
+	g.history = g.history[:len(g.history)-1]
+	g.value = g.history[len(g.history)-1].Value
+	g.version++
+	g.notifyWatchersLocked(old)
+	return nil
+}
+
+// recordHistoryLocked retains the current value, discarding the oldest retained value if needed.
+// It is a no-op if the WithHistory option was not provided. The caller must hold the writer lock.
+func (g *RWGuarded[V]) recordHistoryLocked() {
+	if g.maxHistory <= 0 {
+		return
+	}
+	if len(g.history) > g.maxHistory {
+		// Shift in place rather than reslicing, so the backing array doesn't grow without bound.
+		n := copy(g.history, g.history[1:])
+		g.history[n] = HistoryEntry[V]{}
+		g.history = g.history[:n]
+	}
+	g.history = append(g.history, HistoryEntry[V]{Value: g.value, Time: time.Now()})
+}
@@ -0,0 +1,157 @@
+package rwguarded
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// historyValues returns the values of the entries returned by g.History().
+func historyValues[V any](g *RWGuarded[V]) []V {
+	var values []V
+	for _, entry := range g.History() {
+		values = append(values, entry.Value)
+	}
+	return values
+}
+
+func TestHistoryRetainsMostRecentValues(t *testing.T) {
+	t.Parallel()
+
+	g := New[string]("a", WithHistory(2))
+	g.Set("b")
+	g.Set("c")
+	_ = g.Update(func(val *string) error {
+		*val = "d"
+		return nil
+	})
+
+	history := g.History()
+	if got, want := len(history), 3; got != want {
+		t.Fatalf("len(History()) got %d, want %d", got, want)
+	}
+	for i, want := range []string{"b", "c", "d"} {
+		if got := history[i].Value; got != want {
+			t.Errorf("History()[%d].Value got %q, want %q", i, got, want)
+		}
+	}
+	for i := 1; i < len(history); i++ {
+		if history[i].Time.Before(history[i-1].Time) {
+			t.Errorf("History()[%d].Time %v is before History()[%d].Time %v", i, history[i].Time, i-1, history[i-1].Time)
+		}
+	}
+}
+
+func TestRollback(t *testing.T) {
+	t.Parallel()
+
+	g := New[string]("a", WithHistory(2))
+	g.Set("b")
+	g.Set("c")
+
+	for _, want := range []string{"b", "a"} {
+		if err := g.Rollback(); err != nil {
+			t.Fatalf("Rollback() failed with error %v", err)
+		}
+		if got := g.Get(); got != want {
+			t.Errorf("Get() after Rollback() got %q, want %q", got, want)
+		}
+	}
+	if err := g.Rollback(); err != ErrNoHistory {
+		t.Errorf("Rollback() got error %v, want %v", err, ErrNoHistory)
+	}
+	if got, want := g.Get(), "a"; got != want {
+		t.Errorf("Get() after failed Rollback() got %q, want %q", got, want)
+	}
+	if _, version := g.GetVersioned(); version != 4 {
+		t.Errorf("GetVersioned() got version %d, want %d", version, 4)
+	}
+}
+
+func TestRollbackNotifiesWatchers(t *testing.T) {
+	t.Parallel()
+
+	g := New[int](1, WithHistory(1))
+	g.Set(2)
+	ch := g.Watch(t.Context())
+
+	if err := g.Rollback(); err != nil {
+		t.Fatalf("Rollback() failed with error %v", err)
+	}
+	if got := receive(t, ch); got != 1 {
+		t.Errorf("Watch() got %d, want %d", got, 1)
+	}
+}
+
+func TestHistoryDisabledByDefault(t *testing.T) {
+	t.Parallel()
+
+	g := New[int](1)
+	g.Set(2)
+
+	if got := g.History(); got != nil {
+		t.Errorf("History() got %v, want nil", got)
+	}
+	if err := g.Rollback(); err != ErrNoHistory {
+		t.Errorf("Rollback() got error %v, want %v", err, ErrNoHistory)
+	}
+}
+
+func TestHistoryIgnoresRejectedWrites(t *testing.T) {
+	t.Parallel()
+
+	g, err := NewValidated(1, validateNonNegative, WithHistory(3))
+	if err != nil {
+		t.Fatalf("NewValidated() failed with error %v", err)
+	}
+	_ = g.TrySet(-1)
+	_ = g.SetContext(context.Background(), 2)
+
+	values := historyValues(g)
+	if len(values) != 2 || values[0] != 1 || values[1] != 2 {
+		t.Errorf("History() got values %v, want %v", values, []int{1, 2})
+	}
+}
+
+func TestWithHistoryPanicsOnMapTypes(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		ctor func()
+	}{
+		{
+			name: "map",
+			ctor: func() { NewMap[string, int](WithHistory(1)) },
+		},
+		{
+			name: "checked_map",
+			ctor: func() { NewCheckedMap[string, int](WithHistory(1)) },
+		},
+		{
+			name: "sharded_map",
+			ctor: func() { NewShardedMap[string, int](2, WithHistory(1)) },
+		},
+		{
+			name: "lru",
+			ctor: func() { NewLRU[string, int](1, nil, WithHistory(1)) },
+		},
+		{
+			name: "expiring_map",
+			ctor: func() {
+				NewExpiringMap[string, int](time.Minute, nil, WithJanitorInterval(0), WithOptions(WithHistory(1)))
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			defer func() {
+				if recover() == nil {
+					t.Errorf("constructor with WithHistory() did not panic, but should have")
+				}
+			}()
+			tc.ctor()
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package rwguarded
+
+import (
+	"iter"
+	"maps"
+)
+
+// All returns an iterator over the items of the underlying map, in unspecified order.
+//
+// Each iteration works on a snapshot of the map taken under the reader lock when the iteration
+// starts, so it observes a consistent state of the map: writes that complete before the iteration
+// starts are all reflected, and writes during the iteration are not. Since no lock is held while
+// iterating, the loop body may call any method of this [Map]. The cost of the snapshot is a copy
+// of the map; use [Map.Range] to avoid it.
+func (m *Map[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.rwLock.RLock("Map.All")
+		snapshot := maps.Clone(m.valueByKey)
+		m.rwLock.RUnlock()
+
+		for k, v := range snapshot {
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the keys of the underlying map, in unspecified order. It has the
+// same consistency semantics as [Map.All].
+func (m *Map[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		m.rwLock.RLock("Map.Keys")
+		keys := make([]K, 0, len(m.valueByKey))
+		for k := range m.valueByKey {
+			keys = append(keys, k)
+		}
+		m.rwLock.RUnlock()
+
+		for _, k := range keys {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the values of the underlying map, in unspecified order. It has
+// the same consistency semantics as [Map.All].
+func (m *Map[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		m.rwLock.RLock("Map.Values")
+		values := make([]V, 0, len(m.valueByKey))
+		for _, v := range m.valueByKey {
+			values = append(values, v)
+		}
+		m.rwLock.RUnlock()
+
+		for _, v := range values {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Range calls f for each item of the underlying map, in unspecified order, while holding the reader
+// lock. If f returns false, Range stops the iteration.
+//
+// Since the reader lock is held for the whole iteration, Range observes a consistent state of the
+// map without copying it, but writers are blocked until it returns, so f should be fast. f should
+// not call any other method of this [Map], as this may result in a deadlock. Use [Map.All] if the
+// loop body needs to do either.
+func (m *Map[K, V]) Range(f func(key K, value V) bool) {
+	m.rwLock.RLock("Map.Range")
+	defer m.rwLock.RUnlock()
+
+	for k, v := range m.valueByKey {
+		if !f(k, v) {
+			return
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package rwguarded
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestMapIterators(t *testing.T) {
+	t.Parallel()
+
+	want := map[string]int{"a": 1, "b": 2, "c": 3}
+	rwgMap := NewMap[string, int]()
+	for k, v := range want {
+		rwgMap.Store(k, v)
+	}
+
+	if got := maps.Collect(rwgMap.All()); !maps.Equal(got, want) {
+		t.Errorf("All() got %v, want %v", got, want)
+	}
+	if got, want := slices.Sorted(rwgMap.Keys()), []string{"a", "b", "c"}; !slices.Equal(got, want) {
+		t.Errorf("Keys() got %v, want %v", got, want)
+	}
+	if got, want := slices.Sorted(rwgMap.Values()), []int{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("Values() got %v, want %v", got, want)
+	}
+	got := map[string]int{}
+	rwgMap.Range(func(k string, v int) bool {
+		got[k] = v
+		return true
+	})
+	if !maps.Equal(got, want) {
+		t.Errorf("Range() got %v, want %v", got, want)
+	}
+}
+
+func TestMapIteratorsStopEarly(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewMap[int, int]()
+	for i := range 10 {
+		rwgMap.Store(i, i)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		iterate func(stopAfter int) int
+	}{
+		{
+			name: "all",
+			iterate: func(stopAfter int) int {
+				n := 0
+				for range rwgMap.All() {
+					if n++; n == stopAfter {
+						break
+					}
+				}
+				return n
+			},
+		},
+		{
+			name: "keys",
+			iterate: func(stopAfter int) int {
+				n := 0
+				for range rwgMap.Keys() {
+					if n++; n == stopAfter {
+						break
+					}
+				}
+				return n
+			},
+		},
+		{
+			name: "values",
+			iterate: func(stopAfter int) int {
+				n := 0
+				for range rwgMap.Values() {
+					if n++; n == stopAfter {
+						break
+					}
+				}
+				return n
+			},
+		},
+		{
+			name: "range",
+			iterate: func(stopAfter int) int {
+				n := 0
+				rwgMap.Range(func(int, int) bool {
+					n++
+					return n < stopAfter
+				})
+				return n
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.iterate(3); got != 3 {
+				t.Errorf("iterated over %d items, want %d", got, 3)
+			}
+		})
+	}
+}
+
+func TestMapAllAllowsWritesDuringIteration(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewCheckedMap[int, int]()
+	for i := range 10 {
+		rwgMap.Store(i, i)
+	}
+
+	// Since All iterates over a snapshot, the loop body can write to the map without deadlocking,
+	// and the writes are not observed by the iteration.
+	n := 0
+	for k, v := range rwgMap.All() {
+		rwgMap.Store(k+100, v)
+		n++
+	}
+	if n != 10 {
+		t.Errorf("All() iterated over %d items, want %d", n, 10)
+	}
+	if got := rwgMap.Count(); got != 20 {
+		t.Errorf("Count() got %d, want %d", got, 20)
+	}
+}
+
+func TestMapIteratorsAreConsistentWithConcurrentWriters(t *testing.T) {
+	t.Parallel()
+
+	// The writers move units between keys atomically, so the values of any consistent state of the
+	// map always sum to total.
+	const total = 100
+	keys := []string{"a", "b", "c", "d"}
+	rwgMap := NewMap[string, int]()
+	rwgMap.Store(keys[0], total)
+
+	stop := make(chan struct{})
+	writers := sync.WaitGroup{}
+	for w := range 4 {
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				from, to := keys[(i+w)%len(keys)], keys[(i+w+1)%len(keys)]
+				_ = UpdateAll(func(tx *Tx) error {
+					valueByKey := TxMap(tx, rwgMap)
+					fromValue, _ := valueByKey.Load(from)
+					toValue, _ := valueByKey.Load(to)
+					if fromValue > 0 {
+						valueByKey.Store(from, fromValue-1)
+						valueByKey.Store(to, toValue+1)
+					}
+					return nil
+				}, rwgMap)
+			}
+		}()
+	}
+
+	for range 200 {
+		sum := 0
+		for _, v := range rwgMap.All() {
+			sum += v
+		}
+		if sum != total {
+			t.Errorf("sum of All() values got %d, want %d", sum, total)
+		}
+
+		sum = 0
+		for v := range rwgMap.Values() {
+			sum += v
+		}
+		if sum != total {
+			t.Errorf("sum of Values() got %d, want %d", sum, total)
+		}
+
+		sum = 0
+		rwgMap.Range(func(_ string, v int) bool {
+			sum += v
+			return true
+		})
+		if sum != total {
+			t.Errorf("sum of Range() values got %d, want %d", sum, total)
+		}
+	}
+	close(stop)
+	writers.Wait()
+}
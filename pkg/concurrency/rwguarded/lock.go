@@ -0,0 +1,200 @@
+package rwguarded
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrReentrantCall is the error a checked [RWGuarded] or [Map] panics with when one of its methods
+// is called from a goroutine that already holds its lock, e.g. from within an updater function.
+// Such calls would otherwise deadlock. See [NewChecked] and [NewCheckedMap].
+var ErrReentrantCall = errors.New("reentrant call")
+
+// lastLockID is the ID most recently assigned to an [rwMutex].
+var lastLockID atomic.Uint64
+
+// rwMutex is a [sync.RWMutex] that additionally supports giving up on acquiring the lock when a
+// context is done, and optionally detects reentrant locking.
+//
+// Each locking method takes the name of the public method acquiring the lock, which is used to
+// report reentrant calls.
+type rwMutex struct {
+	mu sync.RWMutex
+	// id uniquely identifies the lock, defining the order in which UpdateAll acquires locks.
+	id uint64
+
+	// checked indicates whether reentrant locking should be detected.
+	checked bool
+	// holdersMutex guards holders.
+	holdersMutex sync.Mutex
+	// holders maps the ID of each goroutine holding the lock to the name of the method it holds it
+	// for. It is only used if checked is true.
+	holders map[uint64]string
+
+	// stats records lock statistics. It is nil unless the WithStats option was provided.
+	stats *lockStats
+}
+
+func newRWMutex(checked bool, o options) *rwMutex {
+	m := &rwMutex{id: lastLockID.Add(1), checked: checked}
+	if checked {
+		m.holders = make(map[uint64]string)
+	}
+	if o.Stats {
+		m.stats = &lockStats{}
+	}
+	return m
+}
+
+// Lock acquires the writer lock.
+func (m *rwMutex) Lock(method string) {
+	gid := m.checkReentrant(method)
+	start := m.stats.Start()
+	m.mu.Lock()
+	m.stats.WriteAcquired(start)
+	m.addHolder(gid, method)
+}
+
+// Unlock releases the writer lock.
+func (m *rwMutex) Unlock() {
+	m.removeHolder()
+	m.stats.WriteReleasing()
+	m.mu.Unlock()
+}
+
+// RLock acquires a reader lock.
+func (m *rwMutex) RLock(method string) {
+	gid := m.checkReentrant(method)
+	start := m.stats.Start()
+	m.mu.RLock()
+	m.stats.ReadAcquired(start)
+	m.addHolder(gid, method)
+}
+
+// RUnlock releases a reader lock.
+func (m *rwMutex) RUnlock() {
+	m.removeHolder()
+	m.stats.ReadReleasing()
+	m.mu.RUnlock()
+}
+
+// LockContext acquires the writer lock, or returns ctx.Err() if ctx is done first.
+func (m *rwMutex) LockContext(ctx context.Context, method string) error {
+	gid := m.checkReentrant(method)
+	start := m.stats.Start()
+	if err := acquireContext(ctx, m.mu.TryLock, m.mu.Lock, m.mu.Unlock); err != nil {
+		return err
+	}
+	m.stats.WriteAcquired(start)
+	m.addHolder(gid, method)
+	return nil
+}
+
+// RLockContext acquires a reader lock, or returns ctx.Err() if ctx is done first.
+func (m *rwMutex) RLockContext(ctx context.Context, method string) error {
+	gid := m.checkReentrant(method)
+	start := m.stats.Start()
+	if err := acquireContext(ctx, m.mu.TryRLock, m.mu.RLock, m.mu.RUnlock); err != nil {
+		return err
+	}
+	m.stats.ReadAcquired(start)
+	m.addHolder(gid, method)
+	return nil
+}
+
+// checkReentrant panics if the calling goroutine already holds the lock, and otherwise returns
+// the calling goroutine's ID. It is a no-op returning 0 if reentrancy checks are disabled.
+func (m *rwMutex) checkReentrant(method string) uint64 {
+	if !m.checked {
+		return 0
+	}
+	gid := goroutineID()
+
+	m.holdersMutex.Lock()
+	holder, held := m.holders[gid]
+	m.holdersMutex.Unlock()
+	if held {
+		panic(fmt.Errorf("%w: %s called from within %s, which would deadlock", ErrReentrantCall, method, holder))
+	}
+	return gid
+}
+
+// addHolder records that the goroutine with the given ID acquired the lock.
+func (m *rwMutex) addHolder(gid uint64, method string) {
+	if !m.checked {
+		return
+	}
+	m.holdersMutex.Lock()
+	defer m.holdersMutex.Unlock()
+
+	m.holders[gid] = method
+}
+
+// removeHolder records that the calling goroutine is about to release the lock.
+func (m *rwMutex) removeHolder() {
+	if !m.checked {
+		return
+	}
+	gid := goroutineID()
+
+	m.holdersMutex.Lock()
+	defer m.holdersMutex.Unlock()
+
+	delete(m.holders, gid)
+}
+
+// goroutineID returns the ID of the calling goroutine, as parsed from its stack trace. This is far
+// too slow for general use, but acceptable for the opt-in reentrancy checks.
+func goroutineID() uint64 {
+	var buf [64]byte
+	stack := buf[:runtime.Stack(buf[:], false)]
+	// The stack trace starts with "goroutine <id> [".
+	stack = bytes.TrimPrefix(stack, []byte("goroutine "))
+	stack, _, _ = bytes.Cut(stack, []byte(" "))
+	id, err := strconv.ParseUint(string(stack), 10, 64)
+	if err != nil {
+		panic(fmt.Sprintf("rwguarded: failed to parse goroutine ID: %v", err))
+	}
+	return id
+}
+
+// acquireContext acquires a lock using the provided functions, giving up if ctx is done first.
+//
+// If the lock can't be acquired immediately, a goroutine blocks on it instead of the caller. This
+// preserves the fairness of [sync.RWMutex] (e.g. a waiting writer still blocks new readers), which
+// polling with TryLock would not. If the caller gives up, the goroutine releases the lock as soon
+// as it acquires it, so a lock that is never released leaks one goroutine per abandoned call.
+func acquireContext(ctx context.Context, tryLock func() bool, lock, unlock func()) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if tryLock() {
+		return nil
+	}
+
+	acquired := make(chan struct{})
+	abandoned := make(chan struct{})
+	go func() {
+		lock()
+		select {
+		case acquired <- struct{}{}:
+			// Ownership of the lock has been handed to the caller.
+		case <-abandoned:
+			unlock()
+		}
+	}()
+
+	select {
+	case <-acquired:
+		return nil
+	case <-ctx.Done():
+		close(abandoned)
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,176 @@
+package rwguarded
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recoverError calls f and returns the error it panicked with, or nil if it didn't panic.
+func recoverError(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var ok bool
+			if err, ok = r.(error); !ok {
+				err = fmt.Errorf("non-error panic: %v", r)
+			}
+		}
+	}()
+	f()
+	return nil
+}
+
+func TestGoroutineID(t *testing.T) {
+	t.Parallel()
+
+	mainID := goroutineID()
+	if mainID == 0 {
+		t.Errorf("goroutineID() returned 0")
+	}
+	if got := goroutineID(); got != mainID {
+		t.Errorf("goroutineID() returned %d, then %d in the same goroutine", mainID, got)
+	}
+
+	otherID := make(chan uint64)
+	go func() {
+		otherID <- goroutineID()
+	}()
+	if got := <-otherID; got == mainID {
+		t.Errorf("goroutineID() returned %d in two different goroutines", got)
+	}
+}
+
+func TestCheckedValueDetectsReentrantCalls(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name        string
+		call        func(g *RWGuarded[int])
+		wantMethods []string
+	}{
+		{
+			name: "Get_from_Update",
+			call: func(g *RWGuarded[int]) {
+				_ = g.Update(func(*int) error {
+					_ = g.Get()
+					return nil
+				})
+			},
+			wantMethods: []string{"RWGuarded.Get", "RWGuarded.Update"},
+		},
+		{
+			name: "Set_from_Update",
+			call: func(g *RWGuarded[int]) {
+				_ = g.Update(func(*int) error {
+					g.Set(1)
+					return nil
+				})
+			},
+			wantMethods: []string{"RWGuarded.Set", "RWGuarded.Update"},
+		},
+		{
+			name: "Set_from_View",
+			call: func(g *RWGuarded[int]) {
+				_ = g.View(func(*int) error {
+					g.Set(1)
+					return nil
+				})
+			},
+			wantMethods: []string{"RWGuarded.Set", "RWGuarded.View"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rwgVal := NewChecked[int](0)
+			err := recoverError(func() {
+				tc.call(rwgVal)
+			})
+			if !errors.Is(err, ErrReentrantCall) {
+				t.Fatalf("reentrant call panicked with %v, want %v", err, ErrReentrantCall)
+			}
+			for _, method := range tc.wantMethods {
+				if !strings.Contains(err.Error(), method) {
+					t.Errorf("error %q does not name method %s", err, method)
+				}
+			}
+
+			// The lock must have been released by the panic, and must not consider this goroutine
+			// a holder anymore.
+			rwgVal.Set(2)
+			if got := rwgVal.Get(); got != 2 {
+				t.Errorf("Get() after recovering got %d, want %d", got, 2)
+			}
+		})
+	}
+}
+
+func TestCheckedValueAllowsConcurrentCalls(t *testing.T) {
+	t.Parallel()
+
+	rwgVal := NewChecked[int](0)
+	wg := sync.WaitGroup{}
+	for range 16 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = rwgVal.Update(func(val *int) error {
+				*val++
+				return nil
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			_ = rwgVal.Get()
+		}()
+	}
+	wg.Wait()
+
+	if got := rwgVal.Get(); got != 16 {
+		t.Errorf("Get() got %d, want %d", got, 16)
+	}
+}
+
+func TestCheckedMapDetectsReentrantCalls(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewCheckedMap[string, int]()
+	rwgMap.Store("k", 1)
+	err := recoverError(func() {
+		_ = rwgMap.Update("k", func(v int) (int, error) {
+			_, _ = rwgMap.Load("k")
+			return v, nil
+		})
+	})
+	if !errors.Is(err, ErrReentrantCall) {
+		t.Fatalf("reentrant call panicked with %v, want %v", err, ErrReentrantCall)
+	}
+	for _, method := range []string{"Map.Load", "Map.Update"} {
+		if !strings.Contains(err.Error(), method) {
+			t.Errorf("error %q does not name method %s", err, method)
+		}
+	}
+}
+
+func TestCheckedMapAllowsNestedStoreIfAbsent(t *testing.T) {
+	t.Parallel()
+
+	// StoreIfAbsent doesn't hold the lock while calling the constructor, so nesting is allowed.
+	rwgMap := NewCheckedMap[string, int]()
+	err := recoverError(func() {
+		_, _ = rwgMap.StoreIfAbsent("outer", func() (*int, error) {
+			_, _ = rwgMap.StoreIfAbsent("inner", func() (*int, error) {
+				return ptrTo(1), nil
+			})
+			return ptrTo(2), nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("nested StoreIfAbsent() panicked with %v", err)
+	}
+	if got := rwgMap.Count(); got != 2 {
+		t.Errorf("Count() got %d, want %d", got, 2)
+	}
+}
@@ -0,0 +1,203 @@
+package rwguarded
+
+import (
+	"container/list"
+	"sync/atomic"
+)
+
+// CacheStats describes the effectiveness of an [LRU] cache. All counts are cumulative since the
+// cache was created.
+type CacheStats struct {
+	// Hits is the number of calls to Get that found the key.
+	Hits uint64
+	// Misses is the number of calls to Get that didn't find the key.
+	Misses uint64
+	// Evictions is the number of entries removed to stay within the maximum cost.
+	Evictions uint64
+}
+
+// lruEntry is an entry of an [LRU], stored in its recency list.
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+	cost  int64
+}
+
+// LRU is a size-bounded cache that evicts its least recently used entries once the total cost of
+// its entries exceeds a maximum. By default, each entry costs 1, so the maximum is a maximum number
+// of entries. Unlike [Map], its size is thus bounded, which makes it suitable as a cache. This
+// struct should not be directly instantiated; callers should use the [NewLRU] or [NewLRUWithCost]
+// functions instead.
+//
+// Since [LRU.Get] updates the recency of the entry it finds, it takes the writer lock, so unlike
+// with [Map], reads contend with each other.
+type LRU[K comparable, V any] struct {
+	rwLock *rwMutex
+	// recency holds the entries, most recently used first.
+	recency   *list.List
+	elemByKey map[K]*list.Element
+	totalCost int64
+	maxCost   int64
+	cost      func(key K, value V) int64
+	onEvict   func(key K, value V)
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// NewLRU initializes and returns an [LRU] of the provided types holding at most maxEntries
+// entries, using the provided options. It panics if maxEntries isn't positive.
+//
+// If onEvict isn't nil, it is called with each entry evicted to stay within maxEntries, after the
+// lock has been released. It is not called for entries that are deleted, overwritten, or cleared.
+func NewLRU[K comparable, V any](maxEntries int, onEvict func(key K, value V), opts ...Option) *LRU[K, V] {
+	return NewLRUWithCost(int64(maxEntries), func(K, V) int64 { return 1 }, onEvict, opts...)
+}
+
+// NewLRUWithCost is like [NewLRU], but the size of the cache is bounded by the total cost of its
+// entries, as computed by the provided cost function when each entry is stored, rather than by
+// their number. This allows bounding e.g. the memory used by values of varying sizes. It panics if
+// maxCost isn't positive.
+//
+// Storing an entry whose cost alone exceeds maxCost evicts all entries, including itself.
+func NewLRUWithCost[K comparable, V any](maxCost int64, cost func(key K, value V) int64, onEvict func(key K, value V), opts ...Option) *LRU[K, V] {
+	if maxCost <= 0 {
+		panic("rwguarded: LRU maximum cost must be positive")
+	}
+	return &LRU[K, V]{
+		rwLock:    newRWMutex(false, buildMapOptions(opts)),
+		recency:   list.New(),
+		elemByKey: make(map[K]*list.Element),
+		maxCost:   maxCost,
+		cost:      cost,
+		onEvict:   onEvict,
+	}
+}
+
+// CacheStats returns the hit, miss, and eviction counts of this [LRU].
+func (c *LRU[K, V]) CacheStats() CacheStats {
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+	}
+}
+
+// Clear deletes all entries.
+func (c *LRU[K, V]) Clear() {
+	c.rwLock.Lock("LRU.Clear")
+	defer c.rwLock.Unlock()
+
+	c.recency.Init()
+	c.elemByKey = make(map[K]*list.Element)
+	c.totalCost = 0
+}
+
+// Cost returns the total cost of the entries, which is their number unless created via
+// [NewLRUWithCost].
+func (c *LRU[K, V]) Cost() int64 {
+	c.rwLock.RLock("LRU.Cost")
+	defer c.rwLock.RUnlock()
+
+	return c.totalCost
+}
+
+// Count returns the number of entries.
+func (c *LRU[K, V]) Count() int {
+	c.rwLock.RLock("LRU.Count")
+	defer c.rwLock.RUnlock()
+
+	return len(c.elemByKey)
+}
+
+// Delete deletes the entries at the provided key(s).
+func (c *LRU[K, V]) Delete(keys ...K) {
+	c.rwLock.Lock("LRU.Delete")
+	defer c.rwLock.Unlock()
+
+	for _, k := range keys {
+		if elem, ok := c.elemByKey[k]; ok {
+			c.removeLocked(elem)
+		}
+	}
+}
+
+// Get returns the value associated with the provided key, marking it as the most recently used
+// entry, and counts a hit or a miss. If the key did not exist, the boolean return value will be
+// false.
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.rwLock.Lock("LRU.Get")
+	defer c.rwLock.Unlock()
+
+	elem, ok := c.elemByKey[key]
+	if !ok {
+		c.misses.Add(1)
+		var zero V
+		return zero, false
+	}
+	c.hits.Add(1)
+	c.recency.MoveToFront(elem)
+	return elem.Value.(*lruEntry[K, V]).value, true
+}
+
+// Peek is like [LRU.Get], but neither updates the recency of the entry nor counts a hit or a miss.
+func (c *LRU[K, V]) Peek(key K) (V, bool) {
+	c.rwLock.RLock("LRU.Peek")
+	defer c.rwLock.RUnlock()
+
+	elem, ok := c.elemByKey[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return elem.Value.(*lruEntry[K, V]).value, true
+}
+
+// Store stores the provided value at the provided key as the most recently used entry, then evicts
+// the least recently used entries until the total cost is within the maximum.
+func (c *LRU[K, V]) Store(key K, value V) {
+	evicted := c.storeAndEvict(key, value)
+	if c.onEvict != nil {
+		for _, e := range evicted {
+			c.onEvict(e.key, e.value)
+		}
+	}
+}
+
+// storeAndEvict implements [LRU.Store] under the writer lock, returning the evicted entries.
+func (c *LRU[K, V]) storeAndEvict(key K, value V) []*lruEntry[K, V] {
+	c.rwLock.Lock("LRU.Store")
+	defer c.rwLock.Unlock()
+
+	entry := &lruEntry[K, V]{key: key, value: value, cost: c.cost(key, value)}
+	if elem, ok := c.elemByKey[key]; ok {
+		c.totalCost -= elem.Value.(*lruEntry[K, V]).cost
+		elem.Value = entry
+		c.recency.MoveToFront(elem)
+	} else {
+		c.elemByKey[key] = c.recency.PushFront(entry)
+	}
+	c.totalCost += entry.cost
+
+	var evicted []*lruEntry[K, V]
+	for c.totalCost > c.maxCost {
+		evicted = append(evicted, c.removeLocked(c.recency.Back()))
+		c.evictions.Add(1)
+	}
+	return evicted
+}
+
+// removeLocked removes the provided element from the cache and returns its entry. The caller must
+// hold the writer lock.
+func (c *LRU[K, V]) removeLocked(elem *list.Element) *lruEntry[K, V] {
+	entry := c.recency.Remove(elem).(*lruEntry[K, V])
+	delete(c.elemByKey, entry.key)
+	c.totalCost -= entry.cost
+	return entry
+}
+
+// Stats returns the statistics recorded for the lock of this [LRU]. If the [WithStats] option was
+// not provided, zero statistics are returned.
+func (c *LRU[K, V]) Stats() Stats {
+	return c.rwLock.stats.Snapshot()
+}
@@ -0,0 +1,178 @@
+package rwguarded
+
+import (
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+
+	var evicted []string
+	cache := NewLRU(2, func(k string, _ int) {
+		evicted = append(evicted, k)
+	})
+
+	cache.Store("a", 1)
+	cache.Store("b", 2)
+	// Using "a" makes "b" the least recently used entry.
+	if got, ok := cache.Get("a"); !ok || got != 1 {
+		t.Errorf("Get(%q) got (%d, %t), want (%d, %t)", "a", got, ok, 1, true)
+	}
+	cache.Store("c", 3)
+
+	if !slices.Equal(evicted, []string{"b"}) {
+		t.Errorf("evicted keys got %v, want %v", evicted, []string{"b"})
+	}
+	if _, ok := cache.Peek("b"); ok {
+		t.Errorf("Peek(%q) found evicted entry, but should not have", "b")
+	}
+	if got := cache.Count(); got != 2 {
+		t.Errorf("Count() got %d, want %d", got, 2)
+	}
+}
+
+func TestLRUPeekDoesNotUpdateRecency(t *testing.T) {
+	t.Parallel()
+
+	cache := NewLRU[string, int](2, nil)
+	cache.Store("a", 1)
+	cache.Store("b", 2)
+	_, _ = cache.Peek("a")
+	cache.Store("c", 3)
+
+	if _, ok := cache.Peek("a"); ok {
+		t.Errorf("Peek(%q) found entry that should have been evicted", "a")
+	}
+	if got := cache.CacheStats(); got != (CacheStats{Evictions: 1}) {
+		t.Errorf("CacheStats() got %+v, want %+v", got, CacheStats{Evictions: 1})
+	}
+}
+
+func TestLRUOverwriteUpdatesRecencyWithoutEvicting(t *testing.T) {
+	t.Parallel()
+
+	cache := NewLRU[string, int](2, nil)
+	cache.Store("a", 1)
+	cache.Store("b", 2)
+	cache.Store("a", 10)
+	cache.Store("c", 3)
+
+	if got, ok := cache.Peek("a"); !ok || got != 10 {
+		t.Errorf("Peek(%q) got (%d, %t), want (%d, %t)", "a", got, ok, 10, true)
+	}
+	if _, ok := cache.Peek("b"); ok {
+		t.Errorf("Peek(%q) found entry that should have been evicted", "b")
+	}
+}
+
+func TestLRUWithCost(t *testing.T) {
+	t.Parallel()
+
+	var evicted []string
+	cache := NewLRUWithCost(10, func(_ string, v string) int64 {
+		return int64(len(v))
+	}, func(k string, _ string) {
+		evicted = append(evicted, k)
+	})
+
+	cache.Store("a", "1234")
+	cache.Store("b", "1234")
+	if got := cache.Cost(); got != 8 {
+		t.Errorf("Cost() got %d, want %d", got, 8)
+	}
+	cache.Store("c", "123456")
+	if !slices.Equal(evicted, []string{"a"}) {
+		t.Errorf("evicted keys got %v, want %v", evicted, []string{"a"})
+	}
+	if got := cache.Cost(); got != 10 {
+		t.Errorf("Cost() got %d, want %d", got, 10)
+	}
+
+	// An entry too costly to fit is evicted right away, after all others.
+	cache.Store("huge", "12345678901")
+	if !slices.Equal(evicted, []string{"a", "b", "c", "huge"}) {
+		t.Errorf("evicted keys got %v, want %v", evicted, []string{"a", "b", "c", "huge"})
+	}
+	if _, ok := cache.Peek("huge"); ok {
+		t.Errorf("Peek(%q) found entry exceeding the maximum cost", "huge")
+	}
+	if got := cache.Cost(); got != 0 {
+		t.Errorf("Cost() got %d, want %d", got, 0)
+	}
+}
+
+func TestLRUCacheStats(t *testing.T) {
+	t.Parallel()
+
+	cache := NewLRU[string, int](1, nil)
+	cache.Store("a", 1)
+	_, _ = cache.Get("a")
+	_, _ = cache.Get("a")
+	_, _ = cache.Get("missing")
+	cache.Store("b", 2)
+
+	want := CacheStats{Hits: 2, Misses: 1, Evictions: 1}
+	if got := cache.CacheStats(); got != want {
+		t.Errorf("CacheStats() got %+v, want %+v", got, want)
+	}
+}
+
+func TestLRUDeleteAndClear(t *testing.T) {
+	t.Parallel()
+
+	cache := NewLRU[string, int](10, nil)
+	cache.Store("a", 1)
+	cache.Store("b", 2)
+	cache.Store("c", 3)
+
+	cache.Delete("a", "missing")
+	if got := cache.Count(); got != 2 {
+		t.Errorf("Count() after Delete() got %d, want %d", got, 2)
+	}
+	cache.Clear()
+	if got, gotCost := cache.Count(), cache.Cost(); got != 0 || gotCost != 0 {
+		t.Errorf("Count() and Cost() after Clear() got (%d, %d), want (%d, %d)", got, gotCost, 0, 0)
+	}
+}
+
+func TestLRUConcurrentOpsStayBounded(t *testing.T) {
+	t.Parallel()
+
+	cache := NewLRU[int, int](50, nil)
+	wg := sync.WaitGroup{}
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				cache.Store(w*1000+i, i)
+				_, _ = cache.Get(w*1000 + i/2)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := cache.Count(); got != 50 {
+		t.Errorf("Count() got %d, want %d", got, 50)
+	}
+	stats := cache.CacheStats()
+	if got, want := stats.Hits+stats.Misses, uint64(8*200); got != want {
+		t.Errorf("CacheStats() hits plus misses got %d, want %d", got, want)
+	}
+	if got, want := stats.Evictions, uint64(8*200-50); got != want {
+		t.Errorf("CacheStats().Evictions got %d, want %d", got, want)
+	}
+}
+
+func TestNewLRUPanicsOnInvalidMaximum(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("NewLRU() with zero maximum did not panic, but should have")
+		}
+	}()
+	NewLRU[string, int](0, nil)
+}
@@ -1,42 +1,156 @@
 package rwguarded
 
 import (
+	"context"
 	"errors"
-	"sync"
 )
 
 // ErrUpdateKeyNotFound is returned when the key is not found in the map during an update
 // operation.
 var ErrUpdateKeyNotFound = errors.New("key not found")
 
+// ErrRecursiveCompute is returned from [Map.LoadOrCompute] when called from within the constructor
+// of a value for the same key, which would otherwise wait for itself forever.
+var ErrRecursiveCompute = errors.New("recursive LoadOrCompute call for the same key")
+
+// ErrComputePanicked is returned from [Map.LoadOrCompute] to callers waiting for a value whose
+// constructor panicked.
+var ErrComputePanicked = errors.New("value constructor panicked")
+
 // Map is a thin wrapper around a map that uses a [sync.RWMutex] to synchronize operations. This
 // struct should not be directly instantiated; callers should use the [NewMap] function instead.
 type Map[K comparable, V any] struct {
-	rwLock     *sync.RWMutex
+	rwLock     *rwMutex
 	valueByKey map[K]V
+	// computations holds the LoadOrCompute calls currently constructing a value, by key. It is
+	// guarded by the writer lock, and created on first use.
+	computations map[K]*computation[V]
+	// waitersByKey holds the LoadWait calls waiting for a key to be stored. It is guarded by the
+	// writer lock, and created on first use.
+	waitersByKey map[K]*keyWaiters
+	// subscribers holds the consumers registered via Subscribe. It is guarded by the writer lock.
+	subscribers map[*mapSubscriber[K, V]]struct{}
+	// txChanges holds the state before the running UpdateAll transaction of each key it changed via
+	// TxMap, and txChangedKeys holds those keys in the order they were first changed. They are
+	// guarded by the writer lock, and reset once the transaction ends.
+	txChanges     map[K]txChange[V]
+	txChangedKeys []K
+}
+
+// keyWaiters are the [Map.LoadWait] calls waiting for the same key.
+type keyWaiters struct {
+	// stored is closed once the key is stored.
+	stored chan struct{}
+	// count is the number of waiting calls.
+	count int
+}
+
+// computation is a value being constructed by [Map.LoadOrCompute].
+type computation[V any] struct {
+	// gid is the ID of the goroutine running the constructor.
+	gid uint64
+	// done is closed once value and err are set.
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// NewMap initializes and returns a [Map] of the provided types using the provided options.
+func NewMap[K comparable, V any](opts ...Option) *Map[K, V] {
+	return &Map[K, V]{
+		rwLock:     newRWMutex(false, buildMapOptions(opts)),
+		valueByKey: make(map[K]V),
+	}
 }
 
-// NewMap initializes and returns a [Map] of the provided types.
-func NewMap[K comparable, V any]() *Map[K, V] {
+// NewCheckedMap is like [NewMap], but the returned [Map] detects calls to its methods from a
+// goroutine that already holds its lock, such as calls from within the updater passed to
+// [Map.Update]. Instead of deadlocking, such calls panic with an error wrapping ErrReentrantCall
+// that names the methods involved.
+//
+// The checks add significant overhead to every method call, so this is intended for use in tests
+// and debugging rather than in production.
+func NewCheckedMap[K comparable, V any](opts ...Option) *Map[K, V] {
 	return &Map[K, V]{
-		rwLock:     &sync.RWMutex{},
+		rwLock:     newRWMutex(true, buildMapOptions(opts)),
 		valueByKey: make(map[K]V),
 	}
 }
 
 // Clear clears the underlying map by creating a new one.
 func (m *Map[K, V]) Clear() {
-	m.rwLock.Lock()
+	m.rwLock.Lock("Map.Clear")
 	defer m.rwLock.Unlock()
 
 	// Since the underlying map is not exported and thus nothing should be keeping a reference to
 	// it, we can just make a new one and let the old one get garbage collected.
 	m.valueByKey = make(map[K]V)
+	m.publishLocked(MapEvent[K, V]{Kind: MapEventClear})
+}
+
+// CompareAndDelete deletes the item at the provided key if its value is equal to old according to
+// the provided equality function, which allows comparing values of types that aren't comparable.
+// It returns true if the item was deleted.
+func (m *Map[K, V]) CompareAndDelete(key K, old V, equal func(a, b V) bool) bool {
+	m.rwLock.Lock("Map.CompareAndDelete")
+	defer m.rwLock.Unlock()
+
+	value, ok := m.valueByKey[key]
+	if !ok || !equal(value, old) {
+		return false
+	}
+	m.deleteLocked(key)
+	return true
+}
+
+// CompareAndSwap stores the new value at the provided key if its current value is equal to old
+// according to the provided equality function, which allows comparing values of types that aren't
+// comparable. It returns true if the value was swapped. Missing keys are never swapped.
+func (m *Map[K, V]) CompareAndSwap(key K, old, new V, equal func(a, b V) bool) bool {
+	m.rwLock.Lock("Map.CompareAndSwap")
+	defer m.rwLock.Unlock()
+
+	value, ok := m.valueByKey[key]
+	if !ok || !equal(value, old) {
+		return false
+	}
+	m.storeLocked(key, new)
+	return true
+}
+
+// Compute atomically inserts, updates, or deletes the item at the provided key, depending on the
+// result of the provided compute function. The function is passed the current value and whether
+// the key exists (if not, the value is the zero value). If it returns an error, the map is left
+// unchanged and the error is returned. Otherwise, if keep is true the returned value is stored at
+// the key, and if keep is false the key is deleted.
+//
+// Compute returns the value at the key after the call, and whether the key exists after the call.
+// Since the map is left unchanged on error, these are then the value passed to the compute function
+// and whether the key existed.
+//
+// The compute function should not call any other method of this [Map], as this will result in a
+// deadlock. Use [NewCheckedMap] to detect such calls in tests.
+func (m *Map[K, V]) Compute(key K, compute func(old V, exists bool) (new V, keep bool, err error)) (V, bool, error) {
+	m.rwLock.Lock("Map.Compute")
+	defer m.rwLock.Unlock()
+
+	old, exists := m.valueByKey[key]
+	value, keep, err := compute(old, exists)
+	if err != nil {
+		return old, exists, err
+	}
+	if !keep {
+		m.deleteLocked(key)
+		var zero V
+		return zero, false, nil
+	}
+	m.storeLocked(key, value)
+	return value, true, nil
 }
 
 // Count returns the number of items in the underlying map.
 func (m *Map[K, V]) Count() int {
-	m.rwLock.RLock()
+	m.rwLock.RLock("Map.Count")
 	defer m.rwLock.RUnlock()
 
 	return len(m.valueByKey)
@@ -44,30 +158,233 @@ func (m *Map[K, V]) Count() int {
 
 // Delete deletes the item(s) at the provided key(s) from the underlying map.
 func (m *Map[K, V]) Delete(keys ...K) {
-	m.rwLock.Lock()
+	m.rwLock.Lock("Map.Delete")
 	defer m.rwLock.Unlock()
 
 	for _, k := range keys {
-		delete(m.valueByKey, k)
+		m.deleteLocked(k)
 	}
 }
 
 // Load returns the value associated with the provided key from the underlying map. If the key
 // did not exist, the boolean return value will be false.
 func (m *Map[K, V]) Load(key K) (V, bool) {
-	m.rwLock.RLock()
+	m.rwLock.RLock("Map.Load")
 	defer m.rwLock.RUnlock()
 
 	value, ok := m.valueByKey[key]
 	return value, ok
 }
 
+// LoadAndDelete deletes the item at the provided key, returning its previous value if any. The
+// boolean return value reports whether the key was present.
+func (m *Map[K, V]) LoadAndDelete(key K) (V, bool) {
+	m.rwLock.Lock("Map.LoadAndDelete")
+	defer m.rwLock.Unlock()
+
+	value, loaded := m.valueByKey[key]
+	m.deleteLocked(key)
+	return value, loaded
+}
+
+// LoadContext is like [Map.Load], but gives up and returns ctx.Err() if ctx is done before the
+// reader lock can be acquired.
+func (m *Map[K, V]) LoadContext(ctx context.Context, key K) (V, bool, error) {
+	if err := m.rwLock.RLockContext(ctx, "Map.LoadContext"); err != nil {
+		var zero V
+		return zero, false, err
+	}
+	defer m.rwLock.RUnlock()
+
+	value, ok := m.valueByKey[key]
+	return value, ok, nil
+}
+
+// LoadOrCompute returns the existing value at the provided key if present. Otherwise, it calls the
+// provided constructor and stores the value it returns, unless the constructor returns an error, in
+// which case the error is returned and nothing is stored.
+//
+// Unlike [Map.StoreIfAbsent], the constructor is called at most once at a time per key: callers
+// racing to load the same missing key wait for the single in-progress call and share its result,
+// including its error. This makes LoadOrCompute suitable for values that are expensive or have side
+// effects to construct, such as connections. Since constructor errors are not stored, a later call
+// for the same key calls the constructor again.
+//
+// The constructor is called without holding any lock, so it may call methods of this [Map],
+// including LoadOrCompute for other keys. Calling LoadOrCompute for the same key from within its
+// constructor returns ErrRecursiveCompute. If the constructor panics, the panic is propagated to
+// the caller that called it, and waiting callers get ErrComputePanicked.
+//
+// If a value is stored at the key by other means (e.g. [Map.Store]) while the constructor runs,
+// that value is kept, returned to all callers, and the constructed value is discarded.
+func (m *Map[K, V]) LoadOrCompute(key K, ctor func() (V, error)) (V, error) {
+	// Most calls for an existing key can be served with only a reader lock.
+	m.rwLock.RLock("Map.LoadOrCompute")
+	value, ok := m.valueByKey[key]
+	m.rwLock.RUnlock()
+	if ok {
+		return value, nil
+	}
+
+	m.rwLock.Lock("Map.LoadOrCompute")
+	if value, ok := m.valueByKey[key]; ok {
+		m.rwLock.Unlock()
+		return value, nil
+	}
+	if c, ok := m.computations[key]; ok {
+		m.rwLock.Unlock()
+		if c.gid == goroutineID() {
+			var zero V
+			return zero, ErrRecursiveCompute
+		}
+		<-c.done
+		return c.value, c.err
+	}
+	c := &computation[V]{gid: goroutineID(), done: make(chan struct{})}
+	if m.computations == nil {
+		m.computations = make(map[K]*computation[V])
+	}
+	m.computations[key] = c
+	m.rwLock.Unlock()
+
+	// Make sure waiting callers are released even if the constructor panics.
+	c.err = ErrComputePanicked
+	completed := false
+	defer func() {
+		if !completed {
+			m.finishComputation(key, c)
+		}
+	}()
+	c.value, c.err = ctor()
+	completed = true
+	m.finishComputation(key, c)
+	return c.value, c.err
+}
+
+// finishComputation stores the result of the provided computation for the provided key, and
+// releases the callers waiting for it.
+func (m *Map[K, V]) finishComputation(key K, c *computation[V]) {
+	m.rwLock.Lock("Map.LoadOrCompute")
+	defer m.rwLock.Unlock()
+
+	delete(m.computations, key)
+	if c.err == nil {
+		if existing, ok := m.valueByKey[key]; ok {
+			c.value = existing
+		} else {
+			m.storeLocked(key, c.value)
+		}
+	}
+	close(c.done)
+}
+
+// LoadOrStore returns the existing value at the provided key if present. Otherwise, it stores and
+// returns the provided value. The boolean return value is true if the value was loaded, and false
+// if it was stored.
+//
+// Unlike [Map.StoreIfAbsent], the value is always constructed by the caller, so LoadOrStore is
+// best suited to values that are cheap to create.
+func (m *Map[K, V]) LoadOrStore(key K, value V) (V, bool) {
+	// Most calls for an existing key can be served with only a reader lock.
+	m.rwLock.RLock("Map.LoadOrStore")
+	existing, loaded := m.valueByKey[key]
+	m.rwLock.RUnlock()
+	if loaded {
+		return existing, true
+	}
+
+	m.rwLock.Lock("Map.LoadOrStore")
+	defer m.rwLock.Unlock()
+	if existing, loaded := m.valueByKey[key]; loaded {
+		return existing, true
+	}
+	m.storeLocked(key, value)
+	return value, false
+}
+
+// LoadWait returns the value associated with the provided key. If the key doesn't exist, it waits
+// until another goroutine stores it, and returns the stored value, or gives up and returns
+// ctx.Err() once ctx is done.
+//
+// All methods storing values wake up the waiting calls, including [UpdateAll] for keys stored via
+// [TxMap]. If the key is deleted again before a woken up call gets to load it, the call keeps
+// waiting.
+func (m *Map[K, V]) LoadWait(ctx context.Context, key K) (V, error) {
+	var zero V
+	// Try checking with only a reader lock first, as this is less expensive than obtaining a writer
+	// lock when the key already exists.
+	if err := m.rwLock.RLockContext(ctx, "Map.LoadWait"); err != nil {
+		return zero, err
+	}
+	value, ok := m.valueByKey[key]
+	m.rwLock.RUnlock()
+	if ok {
+		return value, nil
+	}
+
+	for {
+		if err := m.rwLock.LockContext(ctx, "Map.LoadWait"); err != nil {
+			return zero, err
+		}
+		if value, ok := m.valueByKey[key]; ok {
+			m.rwLock.Unlock()
+			return value, nil
+		}
+		w := m.waitersByKey[key]
+		if w == nil {
+			w = &keyWaiters{stored: make(chan struct{})}
+			if m.waitersByKey == nil {
+				m.waitersByKey = make(map[K]*keyWaiters)
+			}
+			m.waitersByKey[key] = w
+		}
+		w.count++
+		m.rwLock.Unlock()
+
+		select {
+		case <-w.stored:
+			// The key was stored, so load it on the next iteration.
+		case <-ctx.Done():
+			m.abandonWait(key, w)
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// abandonWait records that a [Map.LoadWait] call stopped waiting for the provided key, forgetting
+// about the waiters if none are left, so keys that are never stored don't leak.
+func (m *Map[K, V]) abandonWait(key K, w *keyWaiters) {
+	m.rwLock.Lock("Map.LoadWait")
+	defer m.rwLock.Unlock()
+
+	// The waiters may already have been woken up and removed, and replaced by newer ones.
+	if m.waitersByKey[key] != w {
+		return
+	}
+	w.count--
+	if w.count == 0 {
+		delete(m.waitersByKey, key)
+	}
+}
+
 // Store adds an item to the underlying map with the provided key and value.
 func (m *Map[K, V]) Store(key K, value V) {
-	m.rwLock.Lock()
+	m.rwLock.Lock("Map.Store")
 	defer m.rwLock.Unlock()
 
-	m.valueByKey[key] = value
+	m.storeLocked(key, value)
+}
+
+// StoreContext is like [Map.Store], but gives up and returns ctx.Err() if ctx is done before the
+// writer lock can be acquired.
+func (m *Map[K, V]) StoreContext(ctx context.Context, key K, value V) error {
+	if err := m.rwLock.LockContext(ctx, "Map.StoreContext"); err != nil {
+		return err
+	}
+	defer m.rwLock.Unlock()
+
+	m.storeLocked(key, value)
+	return nil
 }
 
 // StoreIfAbsent checks if the given key exists in the map, and if not, executes the given function
@@ -78,7 +395,8 @@ func (m *Map[K, V]) Store(key K, value V) {
 // Note that in scenarios where multiple routines are calling StoreIfAbsent in parallel for the same
 // key, it's possible for valueCtor to be called by all the routines, but only the first routine
 // that succeeds in obtaining the underlying writer lock will write its value to the map at the
-// given key; the other constructed values will be discarded.
+// given key; the other constructed values will be discarded. Use [Map.LoadOrCompute] if the
+// constructor must not be called more than once.
 //
 // For the boolean return value, this method returns true if the value was successfully constructed
 // and added. Otherwise, it returns false, and the reason for not inserting the value can be
@@ -87,7 +405,7 @@ func (m *Map[K, V]) Store(key K, value V) {
 func (m *Map[K, V]) StoreIfAbsent(key K, valueCtor func() (*V, error)) (bool, error) {
 	// Try checking with only a reader lock first, as this is less expensive than obtaining a writer
 	// lock when the key already exists.
-	m.rwLock.RLock()
+	m.rwLock.RLock("Map.StoreIfAbsent")
 	_, found := m.valueByKey[key]
 	m.rwLock.RUnlock()
 	if found {
@@ -106,23 +424,101 @@ func (m *Map[K, V]) StoreIfAbsent(key K, valueCtor func() (*V, error)) (bool, er
 
 	// Obtain the writer lock, check again if the key exists (because another process could have set
 	// the value between when we released the reader lock and now), and if not, set the value.
-	m.rwLock.Lock()
+	m.rwLock.Lock("Map.StoreIfAbsent")
 	defer m.rwLock.Unlock()
 	if _, found := m.valueByKey[key]; found {
 		return false, nil
 	}
-	m.valueByKey[key] = *valPtr
+	m.storeLocked(key, *valPtr)
 	return true, nil
 }
 
+// Swap stores the provided value at the provided key, returning the previous value if any. The
+// boolean return value reports whether the key was present.
+func (m *Map[K, V]) Swap(key K, value V) (V, bool) {
+	m.rwLock.Lock("Map.Swap")
+	defer m.rwLock.Unlock()
+
+	previous, loaded := m.valueByKey[key]
+	m.storeLocked(key, value)
+	return previous, loaded
+}
+
 // Update fetches an existing item from the map, then calls the provided updater function and stores
 // the new value at the provided key.
 //
 // If the provided key was not found, or the updater function fails, this method returns an error.
+//
+// The updater should not call any other method of this [Map], as this will result in a deadlock.
+// Use [NewCheckedMap] to detect such calls in tests.
 func (m *Map[K, V]) Update(key K, updater func(V) (V, error)) error {
-	m.rwLock.Lock()
+	m.rwLock.Lock("Map.Update")
+	defer m.rwLock.Unlock()
+
+	return m.updateLocked(key, updater)
+}
+
+// UpdateContext is like [Map.Update], but gives up and returns ctx.Err() without calling the
+// updater if ctx is done before the writer lock can be acquired.
+func (m *Map[K, V]) UpdateContext(ctx context.Context, key K, updater func(V) (V, error)) error {
+	if err := m.rwLock.LockContext(ctx, "Map.UpdateContext"); err != nil {
+		return err
+	}
 	defer m.rwLock.Unlock()
 
+	return m.updateLocked(key, updater)
+}
+
+// Upsert atomically stores init at the provided key if it doesn't exist, or otherwise the result of
+// calling update with the current value. It returns the stored value. This is a convenience around
+// [Map.Compute] for patterns such as counters and appending to lists.
+//
+// The update function should not call any other method of this [Map], as this will result in a
+// deadlock.
+func (m *Map[K, V]) Upsert(key K, init V, update func(V) V) V {
+	m.rwLock.Lock("Map.Upsert")
+	defer m.rwLock.Unlock()
+
+	value := init
+	if old, exists := m.valueByKey[key]; exists {
+		value = update(old)
+	}
+	m.storeLocked(key, value)
+	return value
+}
+
+// storeLocked stores the provided value at the provided key, waking up the [Map.LoadWait] calls
+// waiting for it and notifying subscribers. All methods storing values must do so via storeLocked.
+// The caller must hold the writer lock.
+func (m *Map[K, V]) storeLocked(key K, value V) {
+	old, hadOld := m.valueByKey[key]
+	m.valueByKey[key] = value
+	m.wakeWaitersLocked(key)
+	m.publishLocked(MapEvent[K, V]{Kind: MapEventStore, Key: key, Old: old, HadOld: hadOld, New: value})
+}
+
+// deleteLocked deletes the provided key, if it exists, notifying subscribers. All methods deleting
+// keys must do so via deleteLocked. The caller must hold the writer lock.
+func (m *Map[K, V]) deleteLocked(key K) {
+	old, ok := m.valueByKey[key]
+	if !ok {
+		return
+	}
+	delete(m.valueByKey, key)
+	m.publishLocked(MapEvent[K, V]{Kind: MapEventDelete, Key: key, Old: old, HadOld: true})
+}
+
+// wakeWaitersLocked wakes up the [Map.LoadWait] calls waiting for the provided key, if any. The
+// caller must hold the writer lock.
+func (m *Map[K, V]) wakeWaitersLocked(key K) {
+	if w, ok := m.waitersByKey[key]; ok {
+		close(w.stored)
+		delete(m.waitersByKey, key)
+	}
+}
+
+// updateLocked implements [Map.Update]. The caller must hold the writer lock.
+func (m *Map[K, V]) updateLocked(key K, updater func(V) (V, error)) error {
 	gotVal, ok := m.valueByKey[key]
 	if !ok {
 		return ErrUpdateKeyNotFound
@@ -132,7 +528,7 @@ func (m *Map[K, V]) Update(key K, updater func(V) (V, error)) error {
 	if err != nil {
 		return err
 	}
-	m.valueByKey[key] = gotVal
+	m.storeLocked(key, gotVal)
 	return nil
 }
 
@@ -0,0 +1,202 @@
+package rwguarded
+
+import "context"
+
+// MapEventKind is the kind of change described by a [MapEvent].
+type MapEventKind int
+
+const (
+	// MapEventStore describes a value being stored at a key, whether or not it existed.
+	MapEventStore MapEventKind = iota
+	// MapEventDelete describes a key being deleted.
+	MapEventDelete
+	// MapEventClear describes all keys being deleted.
+	MapEventClear
+)
+
+// String returns a human-readable name for the event kind.
+func (k MapEventKind) String() string {
+	switch k {
+	case MapEventStore:
+		return "store"
+	case MapEventDelete:
+		return "delete"
+	case MapEventClear:
+		return "clear"
+	default:
+		return "unknown"
+	}
+}
+
+// MapEvent describes a single change to a [Map], as delivered by [Map.Subscribe].
+type MapEvent[K comparable, V any] struct {
+	// Kind is the kind of change.
+	Kind MapEventKind
+	// Key is the key that changed. It is the zero value for MapEventClear.
+	Key K
+	// Old is the value at the key before the change, if HadOld is true.
+	Old V
+	// HadOld is true if the key existed before the change. It is always true for MapEventDelete.
+	HadOld bool
+	// New is the value at the key after a MapEventStore.
+	New V
+	// Dropped is the number of events dropped right before this one because the subscriber fell
+	// behind. It is only set for subscribers using SlowSubscriberDrop.
+	Dropped uint64
+}
+
+// SlowSubscriberPolicy determines what a [Map] does when an event can't be delivered to a
+// subscriber because its buffer is full.
+type SlowSubscriberPolicy int
+
+const (
+	// SlowSubscriberDisconnect closes the subscriber's channel, so it can tell it missed events and
+	// resynchronize, e.g. via [Map.All] followed by a new subscription.
+	SlowSubscriberDisconnect SlowSubscriberPolicy = iota
+	// SlowSubscriberDrop drops the event. The number of dropped events is reported in the Dropped
+	// field of the next delivered event.
+	SlowSubscriberDrop
+	// SlowSubscriberBlock blocks the writer until the subscriber catches up or its context is done.
+	// Since the writer holds the writer lock while blocked, a slow subscriber then stalls all
+	// operations on the map, and a subscriber that calls methods of the map while it has
+	// undelivered events may deadlock.
+	SlowSubscriberBlock
+)
+
+type subscribeOptions struct {
+	// BufferSize is the number of events buffered for the subscriber.
+	BufferSize int
+	// SlowSubscriber is the policy applied when the subscriber's buffer is full.
+	SlowSubscriber SlowSubscriberPolicy
+}
+
+// SubscribeOption allows specifying a configuration option when subscribing to a [Map].
+type SubscribeOption func(*subscribeOptions)
+
+// defaultEventBufferSize is the number of events buffered for a subscriber by default.
+const defaultEventBufferSize = 64
+
+// WithEventBufferSize is an option that sets the number of events buffered for the subscriber
+// before the [SlowSubscriberPolicy] applies. The default is 64.
+func WithEventBufferSize(n int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.BufferSize = n
+	}
+}
+
+// WithSlowSubscriberPolicy is an option that sets the subscriber's [SlowSubscriberPolicy]. The
+// default is [SlowSubscriberDisconnect].
+func WithSlowSubscriberPolicy(policy SlowSubscriberPolicy) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.SlowSubscriber = policy
+	}
+}
+
+// mapSubscriber is a consumer registered via [Map.Subscribe].
+type mapSubscriber[K comparable, V any] struct {
+	ctx    context.Context
+	opts   subscribeOptions
+	events chan MapEvent[K, V]
+	// done is closed along with events, once the subscriber is removed.
+	done chan struct{}
+	// closed indicates whether events has been closed. It is guarded by the writer lock.
+	closed bool
+	// dropped is the number of events dropped since the last delivered one. It is guarded by the
+	// writer lock.
+	dropped uint64
+}
+
+// Subscribe returns a channel that receives an event for every change made to the underlying map,
+// in the order the changes were made, until ctx is done, at which point the channel is closed.
+// Every method changing the map reports its changes, e.g. [Map.Store], [Map.StoreIfAbsent], and
+// [Map.Update] report MapEventStore events, [Map.Delete] reports a MapEventDelete event for each
+// key that existed, and [Map.Clear] reports a single MapEventClear event.
+//
+// Changes made via [TxMap] are reported once [UpdateAll] returns, whether or not it succeeded, as
+// a MapEventDelete event for each key deleted by the transaction and a MapEventStore event for
+// each key it stored, comparing the state of the key before and after the transaction.
+//
+// Events are buffered, and the [SlowSubscriberPolicy] provided via [WithSlowSubscriberPolicy]
+// determines what happens once the buffer is full. Since only subsequent changes are reported,
+// callers that need the current contents should read them via [Map.All] after Subscribe.
+//
+// Old and New are copies made the same way as by [Map.Load], so if values are or contain reference
+// types, they should be replaced rather than modified in place.
+func (m *Map[K, V]) Subscribe(ctx context.Context, opts ...SubscribeOption) <-chan MapEvent[K, V] {
+	o := subscribeOptions{BufferSize: defaultEventBufferSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	s := &mapSubscriber[K, V]{
+		ctx:    ctx,
+		opts:   o,
+		events: make(chan MapEvent[K, V], max(o.BufferSize, 0)),
+		done:   make(chan struct{}),
+	}
+
+	m.rwLock.Lock("Map.Subscribe")
+	if m.subscribers == nil {
+		m.subscribers = make(map[*mapSubscriber[K, V]]struct{})
+	}
+	m.subscribers[s] = struct{}{}
+	m.rwLock.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-s.done:
+			// The subscriber was already removed, e.g. for being too slow.
+			return
+		}
+
+		m.rwLock.Lock("Map.Subscribe")
+		defer m.rwLock.Unlock()
+
+		m.removeSubscriberLocked(s)
+	}()
+	return s.events
+}
+
+// removeSubscriberLocked unregisters the provided subscriber and closes its channel, unless that
+// was already done. The caller must hold the writer lock.
+func (m *Map[K, V]) removeSubscriberLocked(s *mapSubscriber[K, V]) {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.events)
+	close(s.done)
+	delete(m.subscribers, s)
+}
+
+// publishLocked delivers the provided event to all subscribers. The caller must hold the writer
+// lock. Since all deliveries happen under the writer lock, subscribers receive events in the order
+// in which the changes were made.
+func (m *Map[K, V]) publishLocked(e MapEvent[K, V]) {
+	for s := range m.subscribers {
+		// Dropped is per subscriber, so it must not carry over from the previous one.
+		e.Dropped = 0
+		if s.opts.SlowSubscriber == SlowSubscriberDrop {
+			e.Dropped = s.dropped
+		}
+		select {
+		case s.events <- e:
+			s.dropped = 0
+			continue
+		default:
+		}
+
+		switch s.opts.SlowSubscriber {
+		case SlowSubscriberDrop:
+			s.dropped++
+		case SlowSubscriberBlock:
+			select {
+			case s.events <- e:
+			case <-s.ctx.Done():
+				m.removeSubscriberLocked(s)
+			}
+		default:
+			m.removeSubscriberLocked(s)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package rwguarded
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// storeEvent returns the MapEventStore event for storing value at key over old, if hadOld.
+func storeEvent(key string, old int, hadOld bool, value int) MapEvent[string, int] {
+	return MapEvent[string, int]{Kind: MapEventStore, Key: key, Old: old, HadOld: hadOld, New: value}
+}
+
+func TestMapSubscribeReportsChanges(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewMap[string, int]()
+	events := rwgMap.Subscribe(t.Context())
+
+	rwgMap.Store("a", 1)
+	rwgMap.Store("a", 2)
+	_, _ = rwgMap.StoreIfAbsent("b", func() (*int, error) {
+		return ptrTo(3), nil
+	})
+	_ = rwgMap.Update("b", func(v int) (int, error) {
+		return v + 1, nil
+	})
+	rwgMap.Delete("a", "missing")
+	_, _, _ = rwgMap.Compute("b", func(old int, exists bool) (int, bool, error) {
+		return 0, false, nil
+	})
+	rwgMap.Clear()
+
+	for i, want := range []MapEvent[string, int]{
+		storeEvent("a", 0, false, 1),
+		storeEvent("a", 1, true, 2),
+		storeEvent("b", 0, false, 3),
+		storeEvent("b", 3, true, 4),
+		{Kind: MapEventDelete, Key: "a", Old: 2, HadOld: true},
+		{Kind: MapEventDelete, Key: "b", Old: 4, HadOld: true},
+		{Kind: MapEventClear},
+	} {
+		if got := receive(t, events); got != want {
+			t.Errorf("event %d got %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestMapSubscribeReportsUpdateAllChanges(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewMap[string, int]()
+	rwgMap.Store("deleted", 1)
+	rwgMap.Store("changed", 2)
+	for i := range 100 {
+		rwgMap.Store(fmt.Sprint("unchanged", i), i)
+	}
+	events := rwgMap.Subscribe(t.Context())
+
+	err := UpdateAll(func(tx *Tx) error {
+		valueByKey := TxMap(tx, rwgMap)
+		valueByKey.Delete("deleted", "missing")
+		valueByKey.Store("changed", 3)
+		valueByKey.Store("changed", 4)
+		valueByKey.Store("transient", 5)
+		valueByKey.Delete("transient")
+		return nil
+	}, rwgMap)
+	if err != nil {
+		t.Fatalf("UpdateAll() failed with error %v", err)
+	}
+
+	for i, want := range []MapEvent[string, int]{
+		{Kind: MapEventDelete, Key: "deleted", Old: 1, HadOld: true},
+		storeEvent("changed", 2, true, 4),
+	} {
+		if got := receive(t, events); got != want {
+			t.Errorf("event %d got %+v, want %+v", i, got, want)
+		}
+	}
+	// Only the keys changed by the transaction are reported.
+	if got := len(events); got != 0 {
+		t.Errorf("len(events) after UpdateAll() got %d, want %d", got, 0)
+	}
+}
+
+func TestMapSubscribeClosesChannelWhenContextDone(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rwgMap := NewMap[string, int]()
+	events := rwgMap.Subscribe(ctx)
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Errorf("Subscribe() channel received event after cancellation, want closed channel")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for Subscribe() channel to be closed")
+	}
+	// Writes after the subscriber is gone must not block or panic.
+	rwgMap.Store("a", 1)
+}
+
+func TestMapSubscribeSlowSubscriberPolicies(t *testing.T) {
+	t.Parallel()
+
+	t.Run("disconnect", func(t *testing.T) {
+		t.Parallel()
+
+		rwgMap := NewMap[string, int]()
+		events := rwgMap.Subscribe(t.Context(), WithEventBufferSize(1))
+		rwgMap.Store("a", 1)
+		rwgMap.Store("a", 2)
+
+		if got, want := receive(t, events), storeEvent("a", 0, false, 1); got != want {
+			t.Errorf("event got %+v, want %+v", got, want)
+		}
+		if _, ok := <-events; ok {
+			t.Errorf("Subscribe() channel still open after overflow, want closed channel")
+		}
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		t.Parallel()
+
+		rwgMap := NewMap[string, int]()
+		events := rwgMap.Subscribe(t.Context(), WithEventBufferSize(1), WithSlowSubscriberPolicy(SlowSubscriberDrop))
+		otherEvents := rwgMap.Subscribe(t.Context())
+		rwgMap.Store("a", 1)
+		rwgMap.Store("a", 2)
+		rwgMap.Store("a", 3)
+
+		if got, want := receive(t, events), storeEvent("a", 0, false, 1); got != want {
+			t.Errorf("first event got %+v, want %+v", got, want)
+		}
+		rwgMap.Store("a", 4)
+		want := storeEvent("a", 3, true, 4)
+		want.Dropped = 2
+		if got := receive(t, events); got != want {
+			t.Errorf("event after drops got %+v, want %+v", got, want)
+		}
+		// Drops are only reported to the subscriber that missed events.
+		for range 3 {
+			receive(t, otherEvents)
+		}
+		if got, want := receive(t, otherEvents), storeEvent("a", 3, true, 4); got != want {
+			t.Errorf("other subscriber's event got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("block", func(t *testing.T) {
+		t.Parallel()
+
+		rwgMap := NewMap[string, int]()
+		events := rwgMap.Subscribe(t.Context(), WithEventBufferSize(0), WithSlowSubscriberPolicy(SlowSubscriberBlock))
+		stored := make(chan struct{})
+		go func() {
+			defer close(stored)
+			rwgMap.Store("a", 1)
+			rwgMap.Store("a", 2)
+		}()
+
+		for _, want := range []MapEvent[string, int]{
+			storeEvent("a", 0, false, 1),
+			storeEvent("a", 1, true, 2),
+		} {
+			if got := receive(t, events); got != want {
+				t.Errorf("event got %+v, want %+v", got, want)
+			}
+		}
+		receive(t, stored)
+	})
+
+	t.Run("block_released_by_context", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		rwgMap := NewMap[string, int]()
+		_ = rwgMap.Subscribe(ctx, WithEventBufferSize(0), WithSlowSubscriberPolicy(SlowSubscriberBlock))
+		stored := make(chan struct{})
+		go func() {
+			defer close(stored)
+			rwgMap.Store("a", 1)
+		}()
+		cancel()
+		receive(t, stored)
+	})
+}
+
+// subscribeCleanupGoroutines returns the number of goroutines waiting to remove a subscriber.
+func subscribeCleanupGoroutines() int {
+	return strings.Count(goroutineStacks(), ").Subscribe.func")
+}
+
+// TestMapSubscribeDisconnectDoesNotLeak is not parallel, so that the goroutines of other tests'
+// subscribers don't come and go while it counts them.
+func TestMapSubscribeDisconnectDoesNotLeak(t *testing.T) {
+	before := subscribeCleanupGoroutines()
+	rwgMap := NewMap[string, int]()
+	events := rwgMap.Subscribe(context.Background(), WithEventBufferSize(0))
+	rwgMap.Store("a", 1)
+
+	if _, ok := <-events; ok {
+		t.Errorf("Subscribe() channel still open after overflow, want closed channel")
+	}
+	// The cleanup goroutine must exit even though the context is never done.
+	waitFor(t, func() bool {
+		return subscribeCleanupGoroutines() == before
+	})
+}
+
+func TestMapEventKindString(t *testing.T) {
+	t.Parallel()
+
+	for kind, want := range map[MapEventKind]string{
+		MapEventStore:   "store",
+		MapEventDelete:  "delete",
+		MapEventClear:   "clear",
+		MapEventKind(9): "unknown",
+	} {
+		if got := kind.String(); got != want {
+			t.Errorf("MapEventKind(%d).String() got %q, want %q", kind, got, want)
+		}
+	}
+}
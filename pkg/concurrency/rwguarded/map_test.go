@@ -1,10 +1,13 @@
 package rwguarded
 
 import (
+	"context"
 	"errors"
 	"math/rand"
+	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -13,6 +16,58 @@ func ptrTo[T any](v T) *T {
 	return &v
 }
 
+// waitFor polls until cond returns true, failing the test if it doesn't within a few seconds.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met before deadline")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// goroutineStacks returns the stack traces of all goroutines.
+func goroutineStacks() string {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return string(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// computeWaiters returns the number of goroutines started by the test that are blocked in
+// LoadOrCompute, waiting for a value to be constructed by another goroutine.
+func computeWaiters(t *testing.T) int {
+	n := 0
+	for _, stack := range strings.Split(goroutineStacks(), "\n\n") {
+		header, frames, _ := strings.Cut(stack, "\n")
+		if strings.Contains(header, "[chan receive") &&
+			strings.Contains(frames, "."+t.Name()+".func") &&
+			strings.HasPrefix(frames, "github.com/mhoug89/hogo/pkg/concurrency/rwguarded.(*Map[...]).LoadOrCompute(") {
+			n++
+		}
+	}
+	return n
+}
+
+// loadWaiters returns the number of LoadWait calls waiting for the provided key to be stored.
+func loadWaiters[K comparable, V any](m *Map[K, V], key K) int {
+	m.rwLock.Lock("loadWaiters")
+	defer m.rwLock.Unlock()
+
+	w, ok := m.waitersByKey[key]
+	if !ok {
+		return 0
+	}
+	return w.count
+}
+
 func TestMapSerialStoreThenLoad(t *testing.T) {
 	t.Parallel()
 
@@ -358,3 +413,578 @@ func TestMapUpdateError(t *testing.T) {
 	}
 }
 
+func TestMapContextVariantsGiveUpWhileLocked(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewMap[string, int]()
+	rwgMap.Store("k", 1)
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_ = rwgMap.Update("k", func(v int) (int, error) {
+			close(locked)
+			<-release
+			return 2, nil
+		})
+	}()
+	<-locked
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := rwgMap.LoadContext(ctx, "k"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("LoadContext() got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := rwgMap.StoreContext(ctx, "k", 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("StoreContext() got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	err := rwgMap.UpdateContext(ctx, "k", func(v int) (int, error) {
+		t.Errorf("UpdateContext() called the updater despite giving up")
+		return v, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("UpdateContext() got error %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(release)
+	got, ok, err := rwgMap.LoadContext(context.Background(), "k")
+	if err != nil || !ok || got != 2 {
+		t.Errorf("LoadContext() after release got (%d, %t, %v), want (2, true, nil)", got, ok, err)
+	}
+	if err := rwgMap.StoreContext(context.Background(), "k2", 5); err != nil {
+		t.Fatalf("StoreContext() after release failed with error %v", err)
+	}
+	if err := rwgMap.UpdateContext(context.Background(), "k2", func(v int) (int, error) {
+		return v + 1, nil
+	}); err != nil {
+		t.Fatalf("UpdateContext() after release failed with error %v", err)
+	}
+	if got, ok := rwgMap.Load("k2"); !ok || got != 6 {
+		t.Errorf("Load(%q) got (%d, %t), want (6, true)", "k2", got, ok)
+	}
+	if err := rwgMap.UpdateContext(context.Background(), "k404", func(v int) (int, error) {
+		return v, nil
+	}); !errors.Is(err, ErrUpdateKeyNotFound) {
+		t.Errorf("UpdateContext() on missing key got error %v, want %v", err, ErrUpdateKeyNotFound)
+	}
+}
+
+func TestMapLoadOrStore(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewMap[string, int]()
+	if got, loaded := rwgMap.LoadOrStore("a", 1); got != 1 || loaded {
+		t.Errorf("LoadOrStore() of missing key got (%d, %t), want (%d, %t)", got, loaded, 1, false)
+	}
+	if got, loaded := rwgMap.LoadOrStore("a", 2); got != 1 || !loaded {
+		t.Errorf("LoadOrStore() of existing key got (%d, %t), want (%d, %t)", got, loaded, 1, true)
+	}
+}
+
+func TestMapLoadAndDelete(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewMap[string, int]()
+	rwgMap.Store("a", 1)
+	if got, loaded := rwgMap.LoadAndDelete("a"); got != 1 || !loaded {
+		t.Errorf("LoadAndDelete() of existing key got (%d, %t), want (%d, %t)", got, loaded, 1, true)
+	}
+	if got, loaded := rwgMap.LoadAndDelete("a"); got != 0 || loaded {
+		t.Errorf("LoadAndDelete() of missing key got (%d, %t), want (%d, %t)", got, loaded, 0, false)
+	}
+}
+
+func TestMapSwap(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewMap[string, int]()
+	if got, loaded := rwgMap.Swap("a", 1); got != 0 || loaded {
+		t.Errorf("Swap() of missing key got (%d, %t), want (%d, %t)", got, loaded, 0, false)
+	}
+	if got, loaded := rwgMap.Swap("a", 2); got != 1 || !loaded {
+		t.Errorf("Swap() of existing key got (%d, %t), want (%d, %t)", got, loaded, 1, true)
+	}
+	if got, _ := rwgMap.Load("a"); got != 2 {
+		t.Errorf("Load() after Swap() got %d, want %d", got, 2)
+	}
+}
+
+func TestMapCompareAndSwapAndDelete(t *testing.T) {
+	t.Parallel()
+
+	// Slices aren't comparable, which is what the equality funcs are for.
+	equal := func(a, b []string) bool {
+		return strings.Join(a, ",") == strings.Join(b, ",")
+	}
+
+	for _, tc := range []struct {
+		name    string
+		op      func(m *Map[string, []string]) bool
+		wantOK  bool
+		wantVal []string
+	}{
+		{
+			name: "swap_equal",
+			op: func(m *Map[string, []string]) bool {
+				return m.CompareAndSwap("key", []string{"a"}, []string{"b"}, equal)
+			},
+			wantOK:  true,
+			wantVal: []string{"b"},
+		},
+		{
+			name: "swap_not_equal",
+			op: func(m *Map[string, []string]) bool {
+				return m.CompareAndSwap("key", []string{"x"}, []string{"b"}, equal)
+			},
+			wantOK:  false,
+			wantVal: []string{"a"},
+		},
+		{
+			name: "swap_missing_key",
+			op: func(m *Map[string, []string]) bool {
+				return m.CompareAndSwap("key404", nil, []string{"b"}, equal)
+			},
+			wantOK:  false,
+			wantVal: []string{"a"},
+		},
+		{
+			name: "delete_equal",
+			op: func(m *Map[string, []string]) bool {
+				return m.CompareAndDelete("key", []string{"a"}, equal)
+			},
+			wantOK:  true,
+			wantVal: nil,
+		},
+		{
+			name: "delete_not_equal",
+			op: func(m *Map[string, []string]) bool {
+				return m.CompareAndDelete("key", []string{"x"}, equal)
+			},
+			wantOK:  false,
+			wantVal: []string{"a"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rwgMap := NewMap[string, []string]()
+			rwgMap.Store("key", []string{"a"})
+
+			if got := tc.op(rwgMap); got != tc.wantOK {
+				t.Errorf("op got %t, want %t", got, tc.wantOK)
+			}
+			got, ok := rwgMap.Load("key")
+			if ok != (tc.wantVal != nil) || !equal(got, tc.wantVal) {
+				t.Errorf("Load() got (%v, %t), want %v", got, ok, tc.wantVal)
+			}
+		})
+	}
+}
+
+func TestMapLoadOrStoreConcurrentCallersAgree(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewMap[string, int]()
+	results := make([]int, 16)
+	wg := sync.WaitGroup{}
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = rwgMap.LoadOrStore("key", i)
+		}()
+	}
+	wg.Wait()
+
+	for i, got := range results {
+		if got != results[0] {
+			t.Errorf("LoadOrStore() in goroutine %d got %d, want %d", i, got, results[0])
+		}
+	}
+}
+
+func TestMapCompute(t *testing.T) {
+	t.Parallel()
+
+	errCompute := errors.New("compute failed")
+
+	for _, tc := range []struct {
+		name       string
+		key        string
+		compute    func(old int, exists bool) (int, bool, error)
+		wantVal    int
+		wantExists bool
+		wantErr    error
+		wantCount  int
+	}{
+		{
+			name: "insert",
+			key:  "new",
+			compute: func(old int, exists bool) (int, bool, error) {
+				if exists {
+					return 0, false, errors.New("unexpected existing key")
+				}
+				return 5, true, nil
+			},
+			wantVal:    5,
+			wantExists: true,
+			wantCount:  2,
+		},
+		{
+			name: "update",
+			key:  "key",
+			compute: func(old int, exists bool) (int, bool, error) {
+				return old + 1, true, nil
+			},
+			wantVal:    2,
+			wantExists: true,
+			wantCount:  1,
+		},
+		{
+			name: "delete",
+			key:  "key",
+			compute: func(old int, exists bool) (int, bool, error) {
+				return 0, false, nil
+			},
+			wantVal:    0,
+			wantExists: false,
+			wantCount:  0,
+		},
+		{
+			name: "error",
+			key:  "key",
+			compute: func(old int, exists bool) (int, bool, error) {
+				return 100, false, errCompute
+			},
+			wantVal:    1,
+			wantExists: true,
+			wantErr:    errCompute,
+			wantCount:  1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rwgMap := NewMap[string, int]()
+			rwgMap.Store("key", 1)
+
+			got, exists, err := rwgMap.Compute(tc.key, tc.compute)
+			if err != tc.wantErr {
+				t.Fatalf("Compute() got error %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.wantVal || exists != tc.wantExists {
+				t.Errorf("Compute() got (%d, %t), want (%d, %t)", got, exists, tc.wantVal, tc.wantExists)
+			}
+			if loaded, ok := rwgMap.Load(tc.key); loaded != tc.wantVal || ok != tc.wantExists {
+				t.Errorf("Load() got (%d, %t), want (%d, %t)", loaded, ok, tc.wantVal, tc.wantExists)
+			}
+			if got := rwgMap.Count(); got != tc.wantCount {
+				t.Errorf("Count() got %d, want %d", got, tc.wantCount)
+			}
+		})
+	}
+}
+
+func TestMapUpsert(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewMap[string, []string]()
+	appendItem := func(item string) func([]string) []string {
+		return func(items []string) []string {
+			return append(items, item)
+		}
+	}
+
+	rwgMap.Upsert("list", []string{"a"}, appendItem("a"))
+	got := rwgMap.Upsert("list", []string{"b"}, appendItem("b"))
+	if want := "a,b"; strings.Join(got, ",") != want {
+		t.Errorf("Upsert() got %v, want %v", got, want)
+	}
+}
+
+func TestMapUpsertConcurrentCounter(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewMap[string, int]()
+	increment := func(v int) int {
+		return v + 1
+	}
+	wg := sync.WaitGroup{}
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				rwgMap.Upsert("counter", 1, increment)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got, _ := rwgMap.Load("counter"); got != 1600 {
+		t.Errorf("Load() got %d, want %d", got, 1600)
+	}
+}
+
+func TestMapLoadOrComputeCallsCtorOnce(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewMap[string, int]()
+	var ctorCalls atomic.Int32
+	release := make(chan struct{})
+	ctor := func() (int, error) {
+		ctorCalls.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	results := make([]int, 16)
+	wg := sync.WaitGroup{}
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = rwgMap.LoadOrCompute("key", ctor)
+		}()
+	}
+	// Release the constructor once all other callers wait for it.
+	waitFor(t, func() bool {
+		return computeWaiters(t) == len(results)-1
+	})
+	close(release)
+	wg.Wait()
+
+	if got := ctorCalls.Load(); got != 1 {
+		t.Errorf("constructor called %d times, want %d", got, 1)
+	}
+	for i, got := range results {
+		if got != 42 {
+			t.Errorf("LoadOrCompute() in goroutine %d got %d, want %d", i, got, 42)
+		}
+	}
+}
+
+func TestMapLoadOrComputeErrorIsNotStored(t *testing.T) {
+	t.Parallel()
+
+	errCtor := errors.New("constructor failed")
+	rwgMap := NewMap[string, int]()
+
+	if _, err := rwgMap.LoadOrCompute("key", func() (int, error) {
+		return 0, errCtor
+	}); err != errCtor {
+		t.Errorf("LoadOrCompute() got error %v, want %v", err, errCtor)
+	}
+	if _, ok := rwgMap.Load("key"); ok {
+		t.Errorf("Load() found entry after failed LoadOrCompute(), but should not have")
+	}
+	got, err := rwgMap.LoadOrCompute("key", func() (int, error) {
+		return 1, nil
+	})
+	if got != 1 || err != nil {
+		t.Errorf("LoadOrCompute() after failure got (%d, %v), want (%d, %v)", got, err, 1, nil)
+	}
+}
+
+func TestMapNestedLoadOrCompute(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name         string
+		innerKey     string
+		wantInnerErr error
+		wantCount    int
+	}{
+		{
+			name:         "distinct_keys",
+			innerKey:     "innerKey",
+			wantInnerErr: nil,
+			wantCount:    2,
+		},
+		{
+			name:         "same_key",
+			innerKey:     "outerKey",
+			wantInnerErr: ErrRecursiveCompute,
+			wantCount:    1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rwgMap := NewMap[string, string]()
+			var innerErr error
+			got, err := rwgMap.LoadOrCompute("outerKey", func() (string, error) {
+				_, innerErr = rwgMap.LoadOrCompute(tc.innerKey, func() (string, error) {
+					return "innerValue", nil
+				})
+				return "outerValue", nil
+			})
+
+			if got != "outerValue" || err != nil {
+				t.Errorf("outer LoadOrCompute() got (%q, %v), want (%q, %v)", got, err, "outerValue", nil)
+			}
+			if innerErr != tc.wantInnerErr {
+				t.Errorf("inner LoadOrCompute() got error %v, want %v", innerErr, tc.wantInnerErr)
+			}
+			if got := rwgMap.Count(); got != tc.wantCount {
+				t.Errorf("Count() got %d, want %d", got, tc.wantCount)
+			}
+		})
+	}
+}
+
+func TestMapLoadOrComputePanickingCtorReleasesWaiters(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewMap[string, int]()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	panicked := make(chan any, 1)
+	go func() {
+		defer func() {
+			panicked <- recover()
+		}()
+		_, _ = rwgMap.LoadOrCompute("key", func() (int, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+
+	<-started
+	waiterErr := make(chan error, 1)
+	go func() {
+		_, err := rwgMap.LoadOrCompute("key", func() (int, error) {
+			return 1, nil
+		})
+		waiterErr <- err
+	}()
+	waitFor(t, func() bool {
+		return computeWaiters(t) == 1
+	})
+	close(release)
+
+	if got := <-panicked; got != "boom" {
+		t.Errorf("LoadOrCompute() panicked with %v, want %v", got, "boom")
+	}
+	if err := <-waiterErr; err != ErrComputePanicked {
+		t.Errorf("waiting LoadOrCompute() got error %v, want %v", err, ErrComputePanicked)
+	}
+}
+
+func TestMapLoadOrComputeKeepsValueStoredDuringConstruction(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewMap[string, int]()
+	got, err := rwgMap.LoadOrCompute("key", func() (int, error) {
+		rwgMap.Store("key", 1)
+		return 2, nil
+	})
+	if got != 1 || err != nil {
+		t.Errorf("LoadOrCompute() got (%d, %v), want (%d, %v)", got, err, 1, nil)
+	}
+	if got, _ := rwgMap.Load("key"); got != 1 {
+		t.Errorf("Load() got %d, want %d", got, 1)
+	}
+}
+
+func TestMapLoadWaitReturnsExistingValue(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewMap[string, int]()
+	rwgMap.Store("key", 1)
+	if got, err := rwgMap.LoadWait(t.Context(), "key"); got != 1 || err != nil {
+		t.Errorf("LoadWait() got (%d, %v), want (%d, %v)", got, err, 1, nil)
+	}
+}
+
+func TestMapLoadWaitWakesUpOnStore(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name  string
+		store func(m *Map[string, int])
+	}{
+		{
+			name: "store",
+			store: func(m *Map[string, int]) {
+				m.Store("key", 1)
+			},
+		},
+		{
+			name: "store_if_absent",
+			store: func(m *Map[string, int]) {
+				_, _ = m.StoreIfAbsent("key", func() (*int, error) {
+					return ptrTo(1), nil
+				})
+			},
+		},
+		{
+			name: "load_or_compute",
+			store: func(m *Map[string, int]) {
+				_, _ = m.LoadOrCompute("key", func() (int, error) {
+					return 1, nil
+				})
+			},
+		},
+		{
+			name: "upsert",
+			store: func(m *Map[string, int]) {
+				m.Upsert("key", 1, func(v int) int {
+					return v
+				})
+			},
+		},
+		{
+			name: "update_all",
+			store: func(m *Map[string, int]) {
+				_ = UpdateAll(func(tx *Tx) error {
+					TxMap(tx, m).Store("key", 1)
+					return nil
+				}, m)
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rwgMap := NewMap[string, int]()
+			type result struct {
+				value int
+				err   error
+			}
+			results := make(chan result, 3)
+			for range cap(results) {
+				go func() {
+					value, err := rwgMap.LoadWait(t.Context(), "key")
+					results <- result{value, err}
+				}()
+			}
+			waitFor(t, func() bool {
+				return loadWaiters(rwgMap, "key") == cap(results)
+			})
+			tc.store(rwgMap)
+
+			for range cap(results) {
+				if got := receive(t, results); got.value != 1 || got.err != nil {
+					t.Errorf("LoadWait() got (%d, %v), want (%d, %v)", got.value, got.err, 1, nil)
+				}
+			}
+		})
+	}
+}
+
+func TestMapLoadWaitGivesUpWhenContextDone(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewMap[string, int]()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := rwgMap.LoadWait(ctx, "key"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("LoadWait() got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	// Abandoned waits must not leak.
+	if got := len(rwgMap.waitersByKey); got != 0 {
+		t.Errorf("len(waitersByKey) after LoadWait() gave up got %d, want %d", got, 0)
+	}
+}
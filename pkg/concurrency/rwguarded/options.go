@@ -0,0 +1,99 @@
+package rwguarded
+
+import "time"
+
+type options struct {
+	// History is the number of previous values a RWGuarded retains.
+	History int
+	// Stats indicates whether lock statistics should be recorded.
+	Stats bool
+}
+
+// Option allows specifying a configuration option when creating a new [RWGuarded], [Map], or one of
+// the other types of this package.
+type Option func(*options)
+
+// WithHistory is an option that makes the [RWGuarded] retain up to n previous values, along with
+// the times they were written, in addition to its current value. They can be inspected via
+// [RWGuarded.History], and the most recent one restored via [RWGuarded.Rollback]. If n isn't
+// positive, no history is retained. The constructors of [Map] and the other map types panic if
+// this option is provided with a positive n, since they don't retain history.
+//
+// Since the retained values are shallow copies, this option should only be used with values whose
+// reference types (maps, slices, pointers) are replaced rather than modified in place on update.
+func WithHistory(n int) Option {
+	return func(o *options) {
+		o.History = n
+	}
+}
+
+// WithStats is an option that makes the [RWGuarded] or [Map] record statistics about the usage of
+// its lock, which can be retrieved via its Stats method. This helps finding which guarded
+// structures are contended.
+//
+// Recording statistics adds a few clock reads and atomic operations to every method call.
+func WithStats() Option {
+	return func(o *options) {
+		o.Stats = true
+	}
+}
+
+// buildOptions applies the provided options to a new options struct.
+func buildOptions(opts []Option) options {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// buildMapOptions is like buildOptions, but panics if the options request history, which the map
+// types of this package don't support.
+func buildMapOptions(opts []Option) options {
+	o := buildOptions(opts)
+	if o.History > 0 {
+		panic("rwguarded: WithHistory is only supported by RWGuarded")
+	}
+	return o
+}
+
+type expiringMapOptions struct {
+	// Clock is the clock used to determine whether entries have expired.
+	Clock Clock
+	// JanitorInterval is the interval at which the janitor runs. If zero, the TTL of the map is
+	// used.
+	JanitorInterval time.Duration
+	// NoJanitor indicates whether the map should run without a janitor.
+	NoJanitor bool
+	// Options are the options that apply to all types of this package.
+	Options []Option
+}
+
+// ExpiringMapOption allows specifying a configuration option when creating a new [ExpiringMap].
+type ExpiringMapOption func(*expiringMapOptions)
+
+// WithClock is an option that makes the [ExpiringMap] use the provided clock to determine whether
+// entries have expired, instead of the system clock. This allows testing expiry without sleeping.
+func WithClock(clock Clock) ExpiringMapOption {
+	return func(o *expiringMapOptions) {
+		o.Clock = clock
+	}
+}
+
+// WithJanitorInterval is an option that sets how often the janitor of the [ExpiringMap] reclaims
+// expired entries. By default, the janitor runs once per TTL of the map. If d isn't positive, no
+// janitor is started, and expired entries are only reclaimed via [ExpiringMap.DeleteExpired].
+func WithJanitorInterval(d time.Duration) ExpiringMapOption {
+	return func(o *expiringMapOptions) {
+		o.JanitorInterval = d
+		o.NoJanitor = d <= 0
+	}
+}
+
+// WithOptions is an option that applies the provided options, which apply to all types of this
+// package, such as [WithStats], to the [ExpiringMap].
+func WithOptions(opts ...Option) ExpiringMapOption {
+	return func(o *expiringMapOptions) {
+		o.Options = append(o.Options, opts...)
+	}
+}
@@ -0,0 +1,223 @@
+package rwguarded
+
+import (
+	"context"
+	"hash/maphash"
+	"iter"
+	"runtime"
+)
+
+// shardsPerProc is the number of shards per GOMAXPROCS used by [NewShardedMap] by default.
+const shardsPerProc = 4
+
+// ShardedMap is a map that spreads its keys across several independently locked [Map] shards, so
+// that operations on keys in different shards don't contend for the same lock. It offers the same
+// key-value operations and iterators as [Map], and is preferable to it when many goroutines access
+// the map concurrently. Operations that need a consistent view of the whole map, namely
+// [Map.Subscribe], [Map.Snapshot], [Map.SnapshotToFile], and [UpdateAll], are not supported.
+// This struct should not be directly instantiated; callers should use the [NewShardedMap] function
+// instead.
+//
+// Operations on a single key are as atomic as with [Map]. Operations spanning several keys, such
+// as [ShardedMap.Delete] with multiple keys, [ShardedMap.Clear], [ShardedMap.Count], and the
+// iterators, lock one shard at a time, so they are not atomic with respect to writes to other
+// shards.
+type ShardedMap[K comparable, V any] struct {
+	seed   maphash.Seed
+	shards []*Map[K, V]
+}
+
+// NewShardedMap initializes and returns a [ShardedMap] of the provided types with the provided
+// number of shards, each of which is created with the provided options. If shardCount is not
+// positive, a small multiple of [runtime.GOMAXPROCS] is used.
+func NewShardedMap[K comparable, V any](shardCount int, opts ...Option) *ShardedMap[K, V] {
+	if shardCount <= 0 {
+		shardCount = shardsPerProc * runtime.GOMAXPROCS(0)
+	}
+	shards := make([]*Map[K, V], shardCount)
+	for i := range shards {
+		shards[i] = NewMap[K, V](opts...)
+	}
+	return &ShardedMap[K, V]{
+		seed:   maphash.MakeSeed(),
+		shards: shards,
+	}
+}
+
+// shard returns the shard holding the provided key.
+func (m *ShardedMap[K, V]) shard(key K) *Map[K, V] {
+	return m.shards[maphash.Comparable(m.seed, key)%uint64(len(m.shards))]
+}
+
+// Clear clears every shard.
+func (m *ShardedMap[K, V]) Clear() {
+	for _, s := range m.shards {
+		s.Clear()
+	}
+}
+
+// CompareAndDelete is like [Map.CompareAndDelete].
+func (m *ShardedMap[K, V]) CompareAndDelete(key K, old V, equal func(a, b V) bool) bool {
+	return m.shard(key).CompareAndDelete(key, old, equal)
+}
+
+// CompareAndSwap is like [Map.CompareAndSwap].
+func (m *ShardedMap[K, V]) CompareAndSwap(key K, old, new V, equal func(a, b V) bool) bool {
+	return m.shard(key).CompareAndSwap(key, old, new, equal)
+}
+
+// Compute is like [Map.Compute].
+func (m *ShardedMap[K, V]) Compute(key K, compute func(old V, exists bool) (new V, keep bool, err error)) (V, bool, error) {
+	return m.shard(key).Compute(key, compute)
+}
+
+// Count returns the number of items across all shards.
+func (m *ShardedMap[K, V]) Count() int {
+	n := 0
+	for _, s := range m.shards {
+		n += s.Count()
+	}
+	return n
+}
+
+// Delete deletes the item(s) at the provided key(s).
+func (m *ShardedMap[K, V]) Delete(keys ...K) {
+	for _, k := range keys {
+		m.shard(k).Delete(k)
+	}
+}
+
+// Load is like [Map.Load].
+func (m *ShardedMap[K, V]) Load(key K) (V, bool) {
+	return m.shard(key).Load(key)
+}
+
+// LoadAndDelete is like [Map.LoadAndDelete].
+func (m *ShardedMap[K, V]) LoadAndDelete(key K) (V, bool) {
+	return m.shard(key).LoadAndDelete(key)
+}
+
+// LoadContext is like [Map.LoadContext].
+func (m *ShardedMap[K, V]) LoadContext(ctx context.Context, key K) (V, bool, error) {
+	return m.shard(key).LoadContext(ctx, key)
+}
+
+// LoadWait is like [Map.LoadWait].
+func (m *ShardedMap[K, V]) LoadWait(ctx context.Context, key K) (V, error) {
+	return m.shard(key).LoadWait(ctx, key)
+}
+
+// LoadOrCompute is like [Map.LoadOrCompute].
+func (m *ShardedMap[K, V]) LoadOrCompute(key K, ctor func() (V, error)) (V, error) {
+	return m.shard(key).LoadOrCompute(key, ctor)
+}
+
+// LoadOrStore is like [Map.LoadOrStore].
+func (m *ShardedMap[K, V]) LoadOrStore(key K, value V) (V, bool) {
+	return m.shard(key).LoadOrStore(key, value)
+}
+
+// Store is like [Map.Store].
+func (m *ShardedMap[K, V]) Store(key K, value V) {
+	m.shard(key).Store(key, value)
+}
+
+// StoreContext is like [Map.StoreContext].
+func (m *ShardedMap[K, V]) StoreContext(ctx context.Context, key K, value V) error {
+	return m.shard(key).StoreContext(ctx, key, value)
+}
+
+// StoreIfAbsent is like [Map.StoreIfAbsent].
+func (m *ShardedMap[K, V]) StoreIfAbsent(key K, valueCtor func() (*V, error)) (bool, error) {
+	return m.shard(key).StoreIfAbsent(key, valueCtor)
+}
+
+// Swap is like [Map.Swap].
+func (m *ShardedMap[K, V]) Swap(key K, value V) (V, bool) {
+	return m.shard(key).Swap(key, value)
+}
+
+// Update is like [Map.Update].
+func (m *ShardedMap[K, V]) Update(key K, updater func(V) (V, error)) error {
+	return m.shard(key).Update(key, updater)
+}
+
+// UpdateContext is like [Map.UpdateContext].
+func (m *ShardedMap[K, V]) UpdateContext(ctx context.Context, key K, updater func(V) (V, error)) error {
+	return m.shard(key).UpdateContext(ctx, key, updater)
+}
+
+// Upsert is like [Map.Upsert].
+func (m *ShardedMap[K, V]) Upsert(key K, init V, update func(V) V) V {
+	return m.shard(key).Upsert(key, init, update)
+}
+
+// All is like [Map.All], but each shard is snapshotted separately when the iteration reaches it.
+func (m *ShardedMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, s := range m.shards {
+			for k, v := range s.All() {
+				if !yield(k, v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Keys is like [Map.Keys], but each shard is snapshotted separately when the iteration reaches it.
+func (m *ShardedMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for _, s := range m.shards {
+			for k := range s.Keys() {
+				if !yield(k) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Values is like [Map.Values], but each shard is snapshotted separately when the iteration reaches
+// it.
+func (m *ShardedMap[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, s := range m.shards {
+			for v := range s.Values() {
+				if !yield(v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Range is like [Map.Range], but only holds the reader lock of one shard at a time.
+func (m *ShardedMap[K, V]) Range(f func(key K, value V) bool) {
+	for _, s := range m.shards {
+		stopped := false
+		s.Range(func(k K, v V) bool {
+			stopped = !f(k, v)
+			return !stopped
+		})
+		if stopped {
+			return
+		}
+	}
+}
+
+// Stats returns the sum of the statistics recorded for the locks of all shards. If the [WithStats]
+// option was not provided, zero statistics are returned.
+func (m *ShardedMap[K, V]) Stats() Stats {
+	total := Stats{}
+	for _, s := range m.shards {
+		stats := s.Stats()
+		total.ReadAcquisitions += stats.ReadAcquisitions
+		total.WriteAcquisitions += stats.WriteAcquisitions
+		total.ReadWaitTime += stats.ReadWaitTime
+		total.WriteWaitTime += stats.WriteWaitTime
+		total.ReadHoldTime += stats.ReadHoldTime
+		total.WriteHoldTime += stats.WriteHoldTime
+	}
+	return total
+}
@@ -0,0 +1,226 @@
+package rwguarded
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestShardedMapOps(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewShardedMap[int, string](8)
+	for i := range 100 {
+		rwgMap.Store(i, fmt.Sprint(i))
+	}
+	if got := rwgMap.Count(); got != 100 {
+		t.Fatalf("Count() got %d, want %d", got, 100)
+	}
+	for i := range 100 {
+		if got, ok := rwgMap.Load(i); !ok || got != fmt.Sprint(i) {
+			t.Errorf("Load(%d) got (%q, %t), want (%q, %t)", i, got, ok, fmt.Sprint(i), true)
+		}
+	}
+
+	if err := rwgMap.Update(1, func(v string) (string, error) {
+		return v + "!", nil
+	}); err != nil {
+		t.Errorf("Update() failed with error %v", err)
+	}
+	if got, _ := rwgMap.Load(1); got != "1!" {
+		t.Errorf("Load(1) after Update() got %q, want %q", got, "1!")
+	}
+	if err := rwgMap.Update(1000, func(v string) (string, error) {
+		return v, nil
+	}); err != ErrUpdateKeyNotFound {
+		t.Errorf("Update() of missing key got error %v, want %v", err, ErrUpdateKeyNotFound)
+	}
+
+	stored, err := rwgMap.StoreIfAbsent(1, func() (*string, error) {
+		return ptrTo("new"), nil
+	})
+	if stored || err != nil {
+		t.Errorf("StoreIfAbsent() of existing key got (%t, %v), want (%t, %v)", stored, err, false, nil)
+	}
+
+	rwgMap.Delete(1, 2, 3)
+	if got := rwgMap.Count(); got != 97 {
+		t.Errorf("Count() after Delete() got %d, want %d", got, 97)
+	}
+	rwgMap.Clear()
+	if got := rwgMap.Count(); got != 0 {
+		t.Errorf("Count() after Clear() got %d, want %d", got, 0)
+	}
+}
+
+func TestShardedMapContextVariants(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	rwgMap := NewShardedMap[string, int](0)
+	if err := rwgMap.StoreContext(ctx, "a", 1); err != nil {
+		t.Fatalf("StoreContext() failed with error %v", err)
+	}
+	if err := rwgMap.UpdateContext(ctx, "a", func(v int) (int, error) {
+		return v + 1, nil
+	}); err != nil {
+		t.Fatalf("UpdateContext() failed with error %v", err)
+	}
+	if got, ok, err := rwgMap.LoadContext(ctx, "a"); got != 2 || !ok || err != nil {
+		t.Errorf("LoadContext() got (%d, %t, %v), want (%d, %t, %v)", got, ok, err, 2, true, nil)
+	}
+}
+
+func TestShardedMapIterators(t *testing.T) {
+	t.Parallel()
+
+	want := map[int]int{}
+	rwgMap := NewShardedMap[int, int](4)
+	for i := range 50 {
+		want[i] = i * 2
+		rwgMap.Store(i, i*2)
+	}
+
+	if got := maps.Collect(rwgMap.All()); !maps.Equal(got, want) {
+		t.Errorf("All() got %v, want %v", got, want)
+	}
+	keys := 0
+	for range rwgMap.Keys() {
+		keys++
+	}
+	if keys != 50 {
+		t.Errorf("Keys() yielded %d keys, want %d", keys, 50)
+	}
+	sum := 0
+	for v := range rwgMap.Values() {
+		sum += v
+	}
+	if want := 49 * 50; sum != want {
+		t.Errorf("sum of Values() got %d, want %d", sum, want)
+	}
+
+	n := 0
+	rwgMap.Range(func(int, int) bool {
+		n++
+		return n < 10
+	})
+	if n != 10 {
+		t.Errorf("Range() stopped after %d items, want %d", n, 10)
+	}
+}
+
+func TestShardedMapStatsSumsShards(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewShardedMap[int, int](4, WithStats())
+	for i := range 20 {
+		rwgMap.Store(i, i)
+		_, _ = rwgMap.Load(i)
+	}
+
+	stats := rwgMap.Stats()
+	if stats.ReadAcquisitions != 20 || stats.WriteAcquisitions != 20 {
+		t.Errorf("Stats() got %d reads and %d writes, want %d and %d", stats.ReadAcquisitions, stats.WriteAcquisitions, 20, 20)
+	}
+}
+
+func TestShardedMapConcurrentStores(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewShardedMap[int, int](0)
+	wg := sync.WaitGroup{}
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				rwgMap.Store(w*100+i, i)
+				_, _ = rwgMap.Load(i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := rwgMap.Count(); got != 800 {
+		t.Errorf("Count() got %d, want %d", got, 800)
+	}
+}
+
+// benchKeys is the number of distinct keys accessed by the map benchmarks.
+const benchKeys = 1024
+
+// benchmarkMapOps runs a workload of 90% loads and 10% stores against the map accessed via load and
+// store. To compare throughput at different levels of parallelism, run the benchmarks with e.g.
+// -cpu 1,4,16,64.
+func benchmarkMapOps(b *testing.B, load func(int) (int, bool), store func(int, int)) {
+	for i := range benchKeys {
+		store(i, i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		// Start each worker at a random point, so that workers don't access the same keys in
+		// lockstep.
+		i := rand.Intn(benchKeys)
+		for pb.Next() {
+			i++
+			key := (i * 31) % benchKeys
+			if i%10 == 0 {
+				store(key, i)
+				continue
+			}
+			_, _ = load(key)
+		}
+	})
+}
+
+func BenchmarkMap(b *testing.B) {
+	m := NewMap[int, int]()
+	benchmarkMapOps(b, m.Load, m.Store)
+}
+
+func BenchmarkShardedMap(b *testing.B) {
+	m := NewShardedMap[int, int](0)
+	benchmarkMapOps(b, m.Load, m.Store)
+}
+
+func TestShardedMapAtomicOps(t *testing.T) {
+	t.Parallel()
+
+	equal := func(a, b int) bool {
+		return a == b
+	}
+	rwgMap := NewShardedMap[string, int](4)
+	if got, loaded := rwgMap.LoadOrStore("a", 1); got != 1 || loaded {
+		t.Errorf("LoadOrStore() got (%d, %t), want (%d, %t)", got, loaded, 1, false)
+	}
+	if got, loaded := rwgMap.Swap("a", 2); got != 1 || !loaded {
+		t.Errorf("Swap() got (%d, %t), want (%d, %t)", got, loaded, 1, true)
+	}
+	if !rwgMap.CompareAndSwap("a", 2, 3, equal) {
+		t.Errorf("CompareAndSwap() got %t, want %t", false, true)
+	}
+	if rwgMap.CompareAndDelete("a", 2, equal) {
+		t.Errorf("CompareAndDelete() got %t, want %t", true, false)
+	}
+	if got, loaded := rwgMap.LoadAndDelete("a"); got != 3 || !loaded {
+		t.Errorf("LoadAndDelete() got (%d, %t), want (%d, %t)", got, loaded, 3, true)
+	}
+}
+
+func TestShardedMapComputeAndUpsert(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewShardedMap[string, int](4)
+	got, exists, err := rwgMap.Compute("a", func(old int, exists bool) (int, bool, error) {
+		return old + 10, true, nil
+	})
+	if got != 10 || !exists || err != nil {
+		t.Errorf("Compute() got (%d, %t, %v), want (%d, %t, %v)", got, exists, err, 10, true, nil)
+	}
+	if got := rwgMap.Upsert("a", 0, func(v int) int { return v * 2 }); got != 20 {
+		t.Errorf("Upsert() got %d, want %d", got, 20)
+	}
+}
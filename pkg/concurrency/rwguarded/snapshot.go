@@ -0,0 +1,155 @@
+package rwguarded
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+)
+
+// Codec encodes and decodes the contents of a [Map] for [Map.Snapshot] and [RestoreMap].
+type Codec interface {
+	// Encode writes the encoding of v to w.
+	Encode(w io.Writer, v any) error
+	// Decode reads the encoding of a value from r and stores it in the value pointed to by v.
+	Decode(r io.Reader, v any) error
+}
+
+// JSONCodec is a [Codec] using [encoding/json]. The keys of maps encoded with it must be strings,
+// integers, or implement [encoding.TextMarshaler].
+var JSONCodec Codec = jsonCodec{}
+
+// GobCodec is a [Codec] using [encoding/gob]. It is more compact and faster than JSONCodec, but
+// values stored as interfaces must be registered via [gob.Register].
+var GobCodec Codec = gobCodec{}
+
+// jsonCodec implements [JSONCodec].
+type jsonCodec struct{}
+
+// Encode implements [Codec].
+func (jsonCodec) Encode(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// Decode implements [Codec].
+func (jsonCodec) Decode(r io.Reader, v any) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// gobCodec implements [GobCodec].
+type gobCodec struct{}
+
+// Encode implements [Codec].
+func (gobCodec) Encode(w io.Writer, v any) error {
+	return gob.NewEncoder(w).Encode(v)
+}
+
+// Decode implements [Codec].
+func (gobCodec) Decode(r io.Reader, v any) error {
+	return gob.NewDecoder(r).Decode(v)
+}
+
+// Snapshot writes the contents of the underlying map to w, encoded with the provided codec. The
+// map is copied under the reader lock, so the snapshot is consistent, and encoded after releasing
+// it, so writers are not blocked while writing to w. It can be loaded back via [RestoreMap].
+func (m *Map[K, V]) Snapshot(w io.Writer, codec Codec) error {
+	m.rwLock.RLock("Map.Snapshot")
+	snapshot := maps.Clone(m.valueByKey)
+	m.rwLock.RUnlock()
+
+	if err := codec.Encode(w, snapshot); err != nil {
+		return fmt.Errorf("failed to encode map snapshot: %w", err)
+	}
+	return nil
+}
+
+// SnapshotToFile is like [Map.Snapshot], but writes the snapshot to the file at the provided path.
+// The file is replaced atomically: the snapshot is written to a temporary file in the same
+// directory, which is then renamed to the path, so readers never observe a partially written
+// snapshot, and a failed snapshot leaves any previous one in place. The directory is synced after
+// the rename, so that the new snapshot survives a crash once SnapshotToFile returns.
+//
+// The file keeps the permissions of the file it replaces. If there is no such file, it is created
+// with mode 0600.
+func (m *Map[K, V]) SnapshotToFile(path string, codec Codec) (err error) {
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary snapshot file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if info, err := os.Stat(path); err == nil {
+		if err := f.Chmod(info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to set permissions of temporary snapshot file: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to stat previous snapshot file: %w", err)
+	}
+
+	if err := m.Snapshot(f, codec); err != nil {
+		return err
+	}
+	// Make sure the contents are durable before the rename makes them visible.
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync temporary snapshot file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary snapshot file: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename temporary snapshot file: %w", err)
+	}
+	return syncDir(dir)
+}
+
+// syncDir makes the entries of the directory at the provided path, such as a file renamed into it,
+// durable.
+func syncDir(path string) error {
+	d, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot directory: %w", err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync snapshot directory: %w", err)
+	}
+	return nil
+}
+
+// RestoreMap initializes and returns a [Map] of the provided types using the provided options,
+// filled with the contents of a snapshot read from r, as written by [Map.Snapshot] with the same
+// codec.
+func RestoreMap[K comparable, V any](r io.Reader, codec Codec, opts ...Option) (*Map[K, V], error) {
+	m := NewMap[K, V](opts...)
+	if err := codec.Decode(r, &m.valueByKey); err != nil {
+		return nil, fmt.Errorf("failed to decode map snapshot: %w", err)
+	}
+	if m.valueByKey == nil {
+		// A snapshot of an empty map may decode as a nil map.
+		m.valueByKey = make(map[K]V)
+	}
+	return m, nil
+}
+
+// RestoreMapFromFile is like [RestoreMap], but reads the snapshot from the file at the provided
+// path, as written by [Map.SnapshotToFile].
+func RestoreMapFromFile[K comparable, V any](path string, codec Codec, opts ...Option) (*Map[K, V], error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot file: %w", err)
+	}
+	defer f.Close()
+
+	return RestoreMap[K, V](f, codec, opts...)
+}
@@ -0,0 +1,178 @@
+package rwguarded
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// snapshotItem is a struct value used to check that codecs preserve nested data.
+type snapshotItem struct {
+	Name string
+	Tags []string
+}
+
+// failingCodec is a [Codec] that always fails.
+type failingCodec struct{}
+
+var errCodecFailed = errors.New("codec failed")
+
+func (failingCodec) Encode(io.Writer, any) error {
+	return errCodecFailed
+}
+
+func (failingCodec) Decode(io.Reader, any) error {
+	return errCodecFailed
+}
+
+// mapContents returns a copy of the contents of m.
+func mapContents[K comparable, V any](m *Map[K, V]) map[K]V {
+	return maps.Collect(m.All())
+}
+
+func TestMapSnapshotAndRestore(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name  string
+		codec Codec
+	}{
+		{name: "json", codec: JSONCodec},
+		{name: "gob", codec: GobCodec},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			want := map[int]snapshotItem{
+				1: {Name: "one", Tags: []string{"a", "b"}},
+				2: {Name: "two"},
+			}
+			rwgMap := NewMap[int, snapshotItem]()
+			for k, v := range want {
+				rwgMap.Store(k, v)
+			}
+
+			buf := bytes.Buffer{}
+			if err := rwgMap.Snapshot(&buf, tc.codec); err != nil {
+				t.Fatalf("Snapshot() failed with error %v", err)
+			}
+			restored, err := RestoreMap[int, snapshotItem](&buf, tc.codec)
+			if err != nil {
+				t.Fatalf("RestoreMap() failed with error %v", err)
+			}
+
+			got := mapContents(restored)
+			if len(got) != len(want) {
+				t.Fatalf("RestoreMap() got %v, want %v", got, want)
+			}
+			for k, v := range want {
+				if got[k].Name != v.Name || strings.Join(got[k].Tags, ",") != strings.Join(v.Tags, ",") {
+					t.Errorf("RestoreMap() got %v at key %d, want %v", got[k], k, v)
+				}
+			}
+		})
+	}
+}
+
+func TestRestoreMapOfEmptySnapshotIsUsable(t *testing.T) {
+	t.Parallel()
+
+	buf := bytes.Buffer{}
+	if err := NewMap[string, int]().Snapshot(&buf, GobCodec); err != nil {
+		t.Fatalf("Snapshot() failed with error %v", err)
+	}
+	restored, err := RestoreMap[string, int](&buf, GobCodec)
+	if err != nil {
+		t.Fatalf("RestoreMap() failed with error %v", err)
+	}
+	restored.Store("key", 1)
+	if got := restored.Count(); got != 1 {
+		t.Errorf("Count() got %d, want %d", got, 1)
+	}
+}
+
+func TestMapSnapshotErrors(t *testing.T) {
+	t.Parallel()
+
+	if err := NewMap[string, int]().Snapshot(io.Discard, failingCodec{}); !errors.Is(err, errCodecFailed) {
+		t.Errorf("Snapshot() got error %v, want %v", err, errCodecFailed)
+	}
+	if _, err := RestoreMap[string, int](strings.NewReader(""), failingCodec{}); !errors.Is(err, errCodecFailed) {
+		t.Errorf("RestoreMap() got error %v, want %v", err, errCodecFailed)
+	}
+	if _, err := RestoreMap[string, int](strings.NewReader("not json"), JSONCodec); err == nil {
+		t.Errorf("RestoreMap() of invalid data did not fail, but should have")
+	}
+}
+
+func TestMapSnapshotToFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "snapshot.json")
+	rwgMap := NewMap[string, int]()
+	rwgMap.Store("a", 1)
+	rwgMap.Store("b", 2)
+
+	if err := rwgMap.SnapshotToFile(path, JSONCodec); err != nil {
+		t.Fatalf("SnapshotToFile() failed with error %v", err)
+	}
+	// A failed snapshot leaves the previous one in place, without leftover temporary files.
+	if err := rwgMap.SnapshotToFile(path, failingCodec{}); !errors.Is(err, errCodecFailed) {
+		t.Errorf("SnapshotToFile() got error %v, want %v", err, errCodecFailed)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() failed with error %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("ReadDir() got %d entries, want %d", len(entries), 1)
+	}
+
+	restored, err := RestoreMapFromFile[string, int](path, JSONCodec)
+	if err != nil {
+		t.Fatalf("RestoreMapFromFile() failed with error %v", err)
+	}
+	if got, want := mapContents(restored), mapContents(rwgMap); !maps.Equal(got, want) {
+		t.Errorf("RestoreMapFromFile() got %v, want %v", got, want)
+	}
+
+	if _, err := RestoreMapFromFile[string, int](filepath.Join(dir, "missing"), JSONCodec); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("RestoreMapFromFile() of missing file got error %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestMapSnapshotToFileKeepsPermissions(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	rwgMap := NewMap[string, int]()
+	mode := func() os.FileMode {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Stat() failed with error %v", err)
+		}
+		return info.Mode().Perm()
+	}
+
+	if err := rwgMap.SnapshotToFile(path, JSONCodec); err != nil {
+		t.Fatalf("SnapshotToFile() failed with error %v", err)
+	}
+	if got, want := mode(), os.FileMode(0o600); got != want {
+		t.Errorf("mode of new snapshot file got %v, want %v", got, want)
+	}
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatalf("Chmod() failed with error %v", err)
+	}
+	if err := rwgMap.SnapshotToFile(path, JSONCodec); err != nil {
+		t.Fatalf("SnapshotToFile() failed with error %v", err)
+	}
+	if got, want := mode(), os.FileMode(0o644); got != want {
+		t.Errorf("mode of replaced snapshot file got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+package rwguarded
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats describes the usage of the lock of a [RWGuarded] or [Map] created with the [WithStats]
+// option. All durations are cumulative since the value was created.
+type Stats struct {
+	// ReadAcquisitions is the number of times a reader lock was acquired.
+	ReadAcquisitions uint64
+	// WriteAcquisitions is the number of times the writer lock was acquired.
+	WriteAcquisitions uint64
+	// ReadWaitTime is the total time spent waiting to acquire reader locks.
+	ReadWaitTime time.Duration
+	// WriteWaitTime is the total time spent waiting to acquire the writer lock.
+	WriteWaitTime time.Duration
+	// ReadHoldTime is the total time during which at least one reader lock was held. Overlapping
+	// reader locks are only counted once.
+	ReadHoldTime time.Duration
+	// WriteHoldTime is the total time during which the writer lock was held.
+	WriteHoldTime time.Duration
+}
+
+// lockStats records statistics for an [rwMutex]. All of its methods are no-ops on a nil receiver,
+// so that locks without statistics only pay for a nil check.
+type lockStats struct {
+	readAcquisitions  atomic.Uint64
+	writeAcquisitions atomic.Uint64
+	readWaitNanos     atomic.Int64
+	writeWaitNanos    atomic.Int64
+	readHoldNanos     atomic.Int64
+	writeHoldNanos    atomic.Int64
+
+	// writeAcquiredAt is the time the writer lock was last acquired. It is only accessed while
+	// holding the writer lock.
+	writeAcquiredAt time.Time
+
+	// readers is the number of reader locks currently held, and readersSince is the time, as
+	// returned by sinceStatsEpoch, at which it last went from zero to one. They are atomics rather
+	// than guarded by a mutex so that readers don't contend on it, at the cost of ReadHoldTime
+	// being slightly off when a reader releases the lock just as another acquires it.
+	readers      atomic.Int64
+	readersSince atomic.Int64
+}
+
+// statsEpoch is the reference point of sinceStatsEpoch.
+var statsEpoch = time.Now()
+
+// sinceStatsEpoch returns the monotonic time elapsed between statsEpoch and t, in nanoseconds.
+func sinceStatsEpoch(t time.Time) int64 {
+	return int64(t.Sub(statsEpoch))
+}
+
+// Start returns the time at which an acquisition attempt started.
+func (s *lockStats) Start() time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// WriteAcquired records that the writer lock was acquired after an attempt that started at start.
+// The caller must hold the writer lock.
+func (s *lockStats) WriteAcquired(start time.Time) {
+	if s == nil {
+		return
+	}
+	now := time.Now()
+	s.writeAcquisitions.Add(1)
+	s.writeWaitNanos.Add(int64(now.Sub(start)))
+	s.writeAcquiredAt = now
+}
+
+// WriteReleasing records that the writer lock is about to be released. The caller must hold the
+// writer lock.
+func (s *lockStats) WriteReleasing() {
+	if s == nil {
+		return
+	}
+	s.writeHoldNanos.Add(int64(time.Since(s.writeAcquiredAt)))
+}
+
+// ReadAcquired records that a reader lock was acquired after an attempt that started at start.
+func (s *lockStats) ReadAcquired(start time.Time) {
+	if s == nil {
+		return
+	}
+	now := time.Now()
+	s.readAcquisitions.Add(1)
+	s.readWaitNanos.Add(int64(now.Sub(start)))
+	if s.readers.Add(1) == 1 {
+		s.readersSince.Store(sinceStatsEpoch(now))
+	}
+}
+
+// ReadReleasing records that a reader lock is about to be released.
+func (s *lockStats) ReadReleasing() {
+	if s == nil {
+		return
+	}
+	// Load the start time before releasing, as the next reader to acquire the lock overwrites it.
+	since := s.readersSince.Load()
+	if s.readers.Add(-1) == 0 {
+		s.readHoldNanos.Add(sinceStatsEpoch(time.Now()) - since)
+	}
+}
+
+// Snapshot returns the statistics recorded so far, or zero statistics on a nil receiver.
+func (s *lockStats) Snapshot() Stats {
+	if s == nil {
+		return Stats{}
+	}
+	return Stats{
+		ReadAcquisitions:  s.readAcquisitions.Load(),
+		WriteAcquisitions: s.writeAcquisitions.Load(),
+		ReadWaitTime:      time.Duration(s.readWaitNanos.Load()),
+		WriteWaitTime:     time.Duration(s.writeWaitNanos.Load()),
+		ReadHoldTime:      time.Duration(s.readHoldNanos.Load()),
+		WriteHoldTime:     time.Duration(s.writeHoldNanos.Load()),
+	}
+}
+
+// Stats returns the statistics recorded for the lock of this [RWGuarded]. If the [WithStats]
+// option was not provided, zero statistics are returned.
+func (g *RWGuarded[V]) Stats() Stats {
+	return g.rwLock.stats.Snapshot()
+}
+
+// Stats returns the statistics recorded for the lock of this [Map]. If the [WithStats] option was
+// not provided, zero statistics are returned.
+func (m *Map[K, V]) Stats() Stats {
+	return m.rwLock.stats.Snapshot()
+}
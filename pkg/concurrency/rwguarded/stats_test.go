@@ -0,0 +1,87 @@
+package rwguarded
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValueStatsCountsAcquisitions(t *testing.T) {
+	t.Parallel()
+
+	rwgVal := New[int](0, WithStats())
+	_ = rwgVal.Get()
+	_ = rwgVal.Get()
+	rwgVal.Set(1)
+	_ = rwgVal.Update(func(val *int) error {
+		*val++
+		return nil
+	})
+
+	stats := rwgVal.Stats()
+	if got, want := stats.ReadAcquisitions, uint64(2); got != want {
+		t.Errorf("Stats().ReadAcquisitions got %d, want %d", got, want)
+	}
+	if got, want := stats.WriteAcquisitions, uint64(2); got != want {
+		t.Errorf("Stats().WriteAcquisitions got %d, want %d", got, want)
+	}
+}
+
+func TestValueStatsRecordsHoldAndWaitTimes(t *testing.T) {
+	t.Parallel()
+
+	const holdFor = 20 * time.Millisecond
+
+	rwgVal := New[int](0, WithStats())
+	locked := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = rwgVal.Update(func(val *int) error {
+			close(locked)
+			time.Sleep(holdFor)
+			return nil
+		})
+	}()
+
+	<-locked
+	// This blocks until the Update above releases the lock.
+	_ = rwgVal.Get()
+	<-done
+
+	stats := rwgVal.Stats()
+	if stats.WriteHoldTime < holdFor {
+		t.Errorf("Stats().WriteHoldTime got %v, want at least %v", stats.WriteHoldTime, holdFor)
+	}
+	if stats.ReadWaitTime <= 0 {
+		t.Errorf("Stats().ReadWaitTime got %v, want > 0", stats.ReadWaitTime)
+	}
+}
+
+func TestValueStatsDisabledByDefault(t *testing.T) {
+	t.Parallel()
+
+	rwgVal := New[int](0)
+	_ = rwgVal.Get()
+	rwgVal.Set(1)
+
+	if got := rwgVal.Stats(); got != (Stats{}) {
+		t.Errorf("Stats() got %+v, want zero stats", got)
+	}
+}
+
+func TestMapStats(t *testing.T) {
+	t.Parallel()
+
+	rwgMap := NewMap[string, int](WithStats())
+	rwgMap.Store("key", 1)
+	_, _ = rwgMap.Load("key")
+	_ = rwgMap.Count()
+
+	stats := rwgMap.Stats()
+	if got, want := stats.ReadAcquisitions, uint64(2); got != want {
+		t.Errorf("Stats().ReadAcquisitions got %d, want %d", got, want)
+	}
+	if got, want := stats.WriteAcquisitions, uint64(1); got != want {
+		t.Errorf("Stats().WriteAcquisitions got %d, want %d", got, want)
+	}
+}
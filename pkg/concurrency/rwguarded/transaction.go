@@ -0,0 +1,271 @@
+package rwguarded
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+)
+
+// Guard is implemented by the types in this package whose writer locks can be acquired together
+// via [UpdateAll], i.e. [*RWGuarded] and [*Map].
+type Guard interface {
+	// guardLock returns the lock guarding the underlying data.
+	guardLock() *rwMutex
+	// beginTx is called once the writer lock is held by UpdateAll, and returns the hooks to call,
+	// still under the lock, once the updater returns.
+	beginTx() txHooks
+}
+
+// txHooks are the functions UpdateAll calls for a single [Guard] once the updater returns.
+type txHooks struct {
+	// validate, if non-nil, checks the data changed by the updater.
+	validate func() error
+	// rollback, if non-nil, restores the data as of the start of the transaction. It is called if
+	// the validation of any guard fails.
+	rollback func()
+	// commit, if non-nil, records the write once all guards passed validation.
+	commit func()
+	// finish, if non-nil, is called once the transaction ends, whether or not it succeeded. It
+	// accounts for changes that are never rolled back.
+	finish func()
+}
+
+// Verify interface compliance:
+var (
+	_ Guard = (*RWGuarded[string])(nil)
+	_ Guard = (*Map[string, string])(nil)
+)
+
+// Tx provides access to the data guarded by the locks held by [UpdateAll], for the duration of the
+// updater function. Use [TxValue] and [TxMap] to access the data.
+type Tx struct {
+	locks map[*rwMutex]struct{}
+	ended bool
+}
+
+// checkHeld panics if the tx has ended or doesn't hold the given lock.
+func (tx *Tx) checkHeld(lock *rwMutex) {
+	if tx.ended {
+		panic("rwguarded: use of Tx after UpdateAll returned")
+	}
+	if _, ok := tx.locks[lock]; !ok {
+		panic("rwguarded: use of Tx with a value that was not passed to UpdateAll")
+	}
+}
+
+// TxValue returns a pointer to the value underlying g, which may be read and changed in place by
+// the updater passed to [UpdateAll]. It panics if g was not passed to UpdateAll, or if called after
+// UpdateAll returned. The pointer must not be retained after the updater returns.
+func TxValue[V any](tx *Tx, g *RWGuarded[V]) *V {
+	tx.checkHeld(g.rwLock)
+	return &g.value
+}
+
+// TxMap returns a view of the map underlying m, which may be used to read and change it in place
+// by the updater passed to [UpdateAll]. It panics if m was not passed to UpdateAll, and the
+// returned view panics if used after UpdateAll returned.
+func TxMap[K comparable, V any](tx *Tx, m *Map[K, V]) TxMapView[K, V] {
+	tx.checkHeld(m.rwLock)
+	return TxMapView[K, V]{tx: tx, m: m}
+}
+
+// TxMapView provides access to the map underlying a [Map] within [UpdateAll], as returned by
+// [TxMap]. It records the keys it changes, so that they can be reported once UpdateAll returns.
+type TxMapView[K comparable, V any] struct {
+	tx *Tx
+	m  *Map[K, V]
+}
+
+// txChange records the state of a key before a transaction first changed it.
+type txChange[V any] struct {
+	old    V
+	hadOld bool
+}
+
+// recordChangeLocked records the state of the provided key before the transaction changes it,
+// unless it was already changed earlier in the transaction. The caller must hold the writer lock.
+func (v TxMapView[K, V]) recordChangeLocked(key K) {
+	m := v.m
+	if _, ok := m.txChanges[key]; ok {
+		return
+	}
+	if m.txChanges == nil {
+		m.txChanges = make(map[K]txChange[V])
+	}
+	old, hadOld := m.valueByKey[key]
+	m.txChanges[key] = txChange[V]{old: old, hadOld: hadOld}
+	m.txChangedKeys = append(m.txChangedKeys, key)
+}
+
+// Load returns the value associated with the provided key, and whether the key exists.
+func (v TxMapView[K, V]) Load(key K) (V, bool) {
+	v.tx.checkHeld(v.m.rwLock)
+	value, ok := v.m.valueByKey[key]
+	return value, ok
+}
+
+// Store associates the provided value with the provided key.
+func (v TxMapView[K, V]) Store(key K, value V) {
+	v.tx.checkHeld(v.m.rwLock)
+	v.recordChangeLocked(key)
+	v.m.valueByKey[key] = value
+}
+
+// Delete deletes the item(s) at the provided key(s).
+func (v TxMapView[K, V]) Delete(keys ...K) {
+	v.tx.checkHeld(v.m.rwLock)
+	for _, key := range keys {
+		if _, ok := v.m.valueByKey[key]; !ok {
+			continue
+		}
+		v.recordChangeLocked(key)
+		delete(v.m.valueByKey, key)
+	}
+}
+
+// Len returns the number of items in the map.
+func (v TxMapView[K, V]) Len() int {
+	v.tx.checkHeld(v.m.rwLock)
+	return len(v.m.valueByKey)
+}
+
+// All returns an iterator over the items in the map. Unlike [Map.All], it iterates over the map
+// directly, so the same rules apply as for changing a map while ranging over it.
+func (v TxMapView[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		v.tx.checkHeld(v.m.rwLock)
+		for key, value := range v.m.valueByKey {
+			if !yield(key, value) {
+				return
+			}
+		}
+	}
+}
+
+// UpdateAll allows performing a read-modify-write transaction across several guarded values while
+// holding all of their writer locks, so no other operation on any of them can interleave with the
+// updater. The updater accesses the values via [TxValue] and [TxMap]. The error value returned
+// from the updater is returned from this method.
+//
+// To avoid deadlocks between concurrent UpdateAll calls with overlapping sets of values, the locks
+// are always acquired in a globally consistent order, regardless of the order in which the values
+// are passed. Values passed more than once are only locked once.
+//
+// Values created via [NewValidated] are validated once the updater succeeds. If any of them fails
+// validation, all such values are restored to their state before the transaction, and the first
+// validation error is returned. The same caveats about shallow copies as for [RWGuarded.Update]
+// apply.
+//
+// Other changes made by the updater are not rolled back if it or a validation returns an error, so
+// the updater should validate before changing anything. Changes made via [TxMap] are still reported
+// as usual in that case. As with [RWGuarded.Update], the updater should not call any method of the
+// values passed to UpdateAll, as this will result in a deadlock.
+func UpdateAll(updater func(tx *Tx) error, guards ...Guard) error {
+	byLock := make(map[*rwMutex]Guard, len(guards))
+	for _, g := range guards {
+		byLock[g.guardLock()] = g
+	}
+	locks := make([]*rwMutex, 0, len(byLock))
+	for lock := range byLock {
+		locks = append(locks, lock)
+	}
+	slices.SortFunc(locks, func(a, b *rwMutex) int {
+		return cmp.Compare(a.id, b.id)
+	})
+
+	tx := &Tx{locks: make(map[*rwMutex]struct{}, len(locks))}
+	hooks := make([]txHooks, 0, len(locks))
+	for _, lock := range locks {
+		lock.Lock("UpdateAll")
+		defer lock.Unlock()
+		tx.locks[lock] = struct{}{}
+		hooks = append(hooks, byLock[lock].beginTx())
+	}
+	defer func() {
+		tx.ended = true
+		for _, h := range hooks {
+			if h.finish != nil {
+				h.finish()
+			}
+		}
+	}()
+
+	if err := updater(tx); err != nil {
+		return err
+	}
+	for _, h := range hooks {
+		if h.validate == nil {
+			continue
+		}
+		if err := h.validate(); err != nil {
+			for _, h := range hooks {
+				if h.rollback != nil {
+					h.rollback()
+				}
+			}
+			return err
+		}
+	}
+	for _, h := range hooks {
+		if h.commit != nil {
+			h.commit()
+		}
+	}
+	return nil
+}
+
+// guardLock implements [Guard].
+func (g *RWGuarded[V]) guardLock() *rwMutex {
+	return g.rwLock
+}
+
+// beginTx implements [Guard]. The returned hooks validate, restore, and record the write the same
+// way [RWGuarded.Update] does.
+func (g *RWGuarded[V]) beginTx() txHooks {
+	var old V
+	if g.validate != nil || len(g.watchers) > 0 {
+		old = g.value
+	}
+	hooks := txHooks{
+		commit: func() {
+			g.commitLocked(old)
+		},
+	}
+	if g.validate != nil {
+		hooks.validate = func() error {
+			return g.validate(g.value)
+		}
+		hooks.rollback = func() {
+			g.value = old
+		}
+	}
+	return hooks
+}
+
+// guardLock implements [Guard].
+func (m *Map[K, V]) guardLock() *rwMutex {
+	return m.rwLock
+}
+
+// beginTx implements [Guard]. Since the writes made via [TxMap] are never rolled back, the
+// returned finish function wakes up the [Map.LoadWait] calls waiting for keys stored by the
+// transaction, and reports its changes to subscribers, even if the transaction fails.
+func (m *Map[K, V]) beginTx() txHooks {
+	return txHooks{
+		finish: func() {
+			for _, key := range m.txChangedKeys {
+				change := m.txChanges[key]
+				value, ok := m.valueByKey[key]
+				switch {
+				case ok:
+					m.wakeWaitersLocked(key)
+					m.publishLocked(MapEvent[K, V]{Kind: MapEventStore, Key: key, Old: change.old, HadOld: change.hadOld, New: value})
+				case change.hadOld:
+					m.publishLocked(MapEvent[K, V]{Kind: MapEventDelete, Key: key, Old: change.old, HadOld: true})
+				}
+			}
+			clear(m.txChanges)
+			m.txChangedKeys = m.txChangedKeys[:0]
+		},
+	}
+}
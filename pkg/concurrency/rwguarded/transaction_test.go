@@ -0,0 +1,236 @@
+package rwguarded
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestUpdateAllTransfersBetweenValues(t *testing.T) {
+	t.Parallel()
+
+	from := New[int](100)
+	to := New[int](0)
+	ledger := NewMap[string, int]()
+
+	err := UpdateAll(func(tx *Tx) error {
+		*TxValue(tx, from) -= 30
+		*TxValue(tx, to) += 30
+		transfers, _ := TxMap(tx, ledger).Load("transfers")
+		TxMap(tx, ledger).Store("transfers", transfers+1)
+		return nil
+	}, from, to, ledger)
+	if err != nil {
+		t.Fatalf("UpdateAll() failed with error %v", err)
+	}
+
+	if got := from.Get(); got != 70 {
+		t.Errorf("from.Get() got %d, want %d", got, 70)
+	}
+	if got := to.Get(); got != 30 {
+		t.Errorf("to.Get() got %d, want %d", got, 30)
+	}
+	if got, _ := ledger.Load("transfers"); got != 1 {
+		t.Errorf("ledger.Load() got %d, want %d", got, 1)
+	}
+	if _, version := from.GetVersioned(); version != 1 {
+		t.Errorf("from.GetVersioned() got version %d, want %d", version, 1)
+	}
+}
+
+func TestUpdateAllReturnsUpdaterError(t *testing.T) {
+	t.Parallel()
+
+	errUpdaterFailed := errors.New("updater failed")
+	g := New[int](1)
+
+	err := UpdateAll(func(tx *Tx) error {
+		return errUpdaterFailed
+	}, g)
+	if err != errUpdaterFailed {
+		t.Errorf("UpdateAll() got error %v, want %v", err, errUpdaterFailed)
+	}
+	if _, version := g.GetVersioned(); version != 0 {
+		t.Errorf("GetVersioned() after failed UpdateAll() got version %d, want %d", version, 0)
+	}
+}
+
+func TestUpdateAllReportsMapChangesOnError(t *testing.T) {
+	t.Parallel()
+
+	errUpdaterFailed := errors.New("updater failed")
+	rwgMap := NewMap[string, int]()
+	events := rwgMap.Subscribe(t.Context())
+	loaded := make(chan int, 1)
+	go func() {
+		value, _ := rwgMap.LoadWait(t.Context(), "key")
+		loaded <- value
+	}()
+	waitFor(t, func() bool {
+		return loadWaiters(rwgMap, "key") == 1
+	})
+
+	err := UpdateAll(func(tx *Tx) error {
+		TxMap(tx, rwgMap).Store("key", 1)
+		return errUpdaterFailed
+	}, rwgMap)
+	if err != errUpdaterFailed {
+		t.Errorf("UpdateAll() got error %v, want %v", err, errUpdaterFailed)
+	}
+
+	// The write is not rolled back, so it must be reported like any other.
+	if got := receive(t, loaded); got != 1 {
+		t.Errorf("LoadWait() got %d, want %d", got, 1)
+	}
+	if got, want := receive(t, events), storeEvent("key", 0, false, 1); got != want {
+		t.Errorf("event got %+v, want %+v", got, want)
+	}
+}
+
+func TestUpdateAllDuplicateGuards(t *testing.T) {
+	t.Parallel()
+
+	g := New[int](1)
+	err := UpdateAll(func(tx *Tx) error {
+		*TxValue(tx, g)++
+		return nil
+	}, g, g)
+	if err != nil {
+		t.Fatalf("UpdateAll() failed with error %v", err)
+	}
+	if got := g.Get(); got != 2 {
+		t.Errorf("Get() got %d, want %d", got, 2)
+	}
+}
+
+func TestUpdateAllPanicsOnValueNotInTx(t *testing.T) {
+	t.Parallel()
+
+	locked := New[int](1)
+	notLocked := New[int](1)
+	err := recoverError(func() {
+		_ = UpdateAll(func(tx *Tx) error {
+			*TxValue(tx, notLocked)++
+			return nil
+		}, locked)
+	})
+	if err == nil {
+		t.Errorf("TxValue() with a value not passed to UpdateAll() did not panic")
+	}
+
+	var leaked *Tx
+	_ = UpdateAll(func(tx *Tx) error {
+		leaked = tx
+		return nil
+	}, locked)
+	if recoverError(func() { _ = TxValue(leaked, locked) }) == nil {
+		t.Errorf("TxValue() after UpdateAll() returned did not panic")
+	}
+}
+
+func TestUpdateAllOppositeOrdersDoNotDeadlock(t *testing.T) {
+	t.Parallel()
+
+	a := New[int](1000)
+	b := New[int](1000)
+	wg := sync.WaitGroup{}
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Half the goroutines pass the values in the opposite order.
+			src, dst := a, b
+			if i%2 == 1 {
+				src, dst = b, a
+			}
+			for range 100 {
+				_ = UpdateAll(func(tx *Tx) error {
+					*TxValue(tx, src)--
+					*TxValue(tx, dst)++
+					return nil
+				}, src, dst)
+			}
+		}()
+		// Concurrently check that no reader observes a partially applied transfer.
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				_ = UpdateAll(func(tx *Tx) error {
+					if sum := *TxValue(tx, a) + *TxValue(tx, b); sum != 2000 {
+						t.Errorf("observed sum %d mid-transfer, want %d", sum, 2000)
+					}
+					return nil
+				}, b, a)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if sum := a.Get() + b.Get(); sum != 2000 {
+		t.Errorf("sum after transfers got %d, want %d", sum, 2000)
+	}
+}
+
+func TestUpdateAllNotifiesWatchers(t *testing.T) {
+	t.Parallel()
+
+	g := New[int](1)
+	changes := make(chan [2]int, 1)
+	ctx := t.Context()
+	g.Subscribe(ctx, func(old, new int) {
+		changes <- [2]int{old, new}
+	})
+
+	_ = UpdateAll(func(tx *Tx) error {
+		*TxValue(tx, g) = 5
+		return nil
+	}, g)
+	if got, want := receive(t, changes), [2]int{1, 5}; got != want {
+		t.Errorf("Subscribe() after UpdateAll() got %v, want %v", got, want)
+	}
+}
+
+func TestUpdateAllCheckedDetectsReentrantCalls(t *testing.T) {
+	t.Parallel()
+
+	g := NewChecked[int](1)
+	err := recoverError(func() {
+		_ = UpdateAll(func(tx *Tx) error {
+			_ = g.Get()
+			return nil
+		}, g)
+	})
+	if !errors.Is(err, ErrReentrantCall) {
+		t.Errorf("reentrant call from UpdateAll() panicked with %v, want %v", err, ErrReentrantCall)
+	}
+}
+
+func TestUpdateAllRestoresValidatedValuesOnValidationError(t *testing.T) {
+	t.Parallel()
+
+	from, err := NewValidated(10, validateNonNegative)
+	if err != nil {
+		t.Fatalf("NewValidated() failed with error %v", err)
+	}
+	to, err := NewValidated(0, validateNonNegative)
+	if err != nil {
+		t.Fatalf("NewValidated() failed with error %v", err)
+	}
+
+	err = UpdateAll(func(tx *Tx) error {
+		*TxValue(tx, from) -= 30
+		*TxValue(tx, to) += 30
+		return nil
+	}, from, to)
+	if err != errNegative {
+		t.Errorf("UpdateAll() got error %v, want %v", err, errNegative)
+	}
+
+	if got, version := from.GetVersioned(); got != 10 || version != 0 {
+		t.Errorf("from.GetVersioned() got (%d, %d), want (%d, %d)", got, version, 10, 0)
+	}
+	if got, version := to.GetVersioned(); got != 0 || version != 0 {
+		t.Errorf("to.GetVersioned() got (%d, %d), want (%d, %d)", got, version, 0, 0)
+	}
+}
@@ -3,51 +3,245 @@
 package rwguarded
 
 import (
-	"sync"
+	"context"
+	"errors"
 )
 
+// ErrVersionConflict is returned from [RWGuarded.SetIfVersion] when the underlying value has been
+// written since the provided version was obtained.
+var ErrVersionConflict = errors.New("version conflict")
+
 // RWGuarded is a thin wrapper around the provided value that uses a [sync.RWMutex] to synchronize
 // operations. This struct should not be directly instantiated; callers should use the [New]
 // function instead.
 type RWGuarded[V any] struct {
-	rwLock *sync.RWMutex
+	rwLock *rwMutex
 	value  V
+	// version is incremented every time the value is written.
+	version uint64
+	// watchers holds the consumers registered via Watch and Subscribe. It is guarded by the writer
+	// lock.
+	watchers map[*watcher[V]]struct{}
+	// validate, if non-nil, must accept every value written. See NewValidated.
+	validate func(V) error
+	// history holds the current value and up to maxHistory previous values, oldest first. It is
+	// guarded by the writer lock, and only used if maxHistory is positive.
+	history    []HistoryEntry[V]
+	maxHistory int
+}
+
+// New initializes and returns a [RWGuarded] of the provided type using the provided options.
+func New[V any](val V, opts ...Option) *RWGuarded[V] {
+	return newRWGuarded(val, false, nil, buildOptions(opts))
+}
+
+// NewChecked is like [New], but the returned [RWGuarded] detects calls to its methods from a
+// goroutine that already holds its lock, such as calls from within the updater passed to
+// [RWGuarded.Update]. Instead of deadlocking, such calls panic with an error wrapping
+// ErrReentrantCall that names the methods involved.
+//
+// The checks add significant overhead to every method call, so this is intended for use in tests
+// and debugging rather than in production.
+func NewChecked[V any](val V, opts ...Option) *RWGuarded[V] {
+	return newRWGuarded(val, true, nil, buildOptions(opts))
+}
+
+// NewValidated is like [New], but every value written to the returned [RWGuarded] must first be
+// accepted by the validate function. Writes of values it rejects leave the old value in place, and
+// the error returned by validate is returned from the method that attempted the write, such as
+// [RWGuarded.TrySet] or [RWGuarded.Update]. The initial value is validated too, and its validation
+// error is returned from this function.
+//
+// The validate function is called while holding the writer lock, so it should be fast and must not
+// call any method of the [RWGuarded] it validates values for.
+func NewValidated[V any](val V, validate func(V) error, opts ...Option) (*RWGuarded[V], error) {
+	if err := validate(val); err != nil {
+		return nil, err
+	}
+	return newRWGuarded(val, false, validate, buildOptions(opts)), nil
 }
 
-// New initializes and returns a [RWGuarded] of the provided type.
-func New[V any](val V) *RWGuarded[V] {
-	return &RWGuarded[V]{
-		rwLock: &sync.RWMutex{},
-		value:  val,
+// newRWGuarded implements the functions creating a [RWGuarded].
+func newRWGuarded[V any](val V, checked bool, validate func(V) error, o options) *RWGuarded[V] {
+	g := &RWGuarded[V]{
+		rwLock:     newRWMutex(checked, o),
+		value:      val,
+		validate:   validate,
+		maxHistory: o.History,
 	}
+	g.recordHistoryLocked()
+	return g
 }
 
-// Get returns the underlying value.
+// Get returns a copy of the underlying value.
+//
+// The copy is shallow: if the value is or contains a map, slice, pointer, or other reference type,
+// the returned copy shares that data with the underlying value, and reading it after Get returns
+// is not synchronized with writers that modify it in place via [RWGuarded.Update]. In that case, or
+// when the value is large enough that copying it is expensive, use [RWGuarded.View] instead.
 func (g *RWGuarded[V]) Get() V {
-	g.rwLock.RLock()
+	g.rwLock.RLock("RWGuarded.Get")
 	defer g.rwLock.RUnlock()
 
 	return g.value
 }
 
+// GetContext is like [RWGuarded.Get], but gives up and returns ctx.Err() if ctx is done before the
+// reader lock can be acquired.
+func (g *RWGuarded[V]) GetContext(ctx context.Context) (V, error) {
+	if err := g.rwLock.RLockContext(ctx, "RWGuarded.GetContext"); err != nil {
+		var zero V
+		return zero, err
+	}
+	defer g.rwLock.RUnlock()
+
+	return g.value, nil
+}
+
+// GetVersioned returns a copy of the underlying value along with its version. The version is
+// incremented every time the value is written, so it can be passed to [RWGuarded.SetIfVersion] to
+// write a new value only if no other write happened in between.
+//
+// The same aliasing caveats as for [RWGuarded.Get] apply to the returned value.
+func (g *RWGuarded[V]) GetVersioned() (V, uint64) {
+	g.rwLock.RLock("RWGuarded.GetVersioned")
+	defer g.rwLock.RUnlock()
+
+	return g.value, g.version
+}
+
+// View allows reading the underlying value in place while holding the reader lock. The viewer
+// function is passed a pointer to the underlying value, which avoids copying it. The error value
+// returned from the viewer is returned from this method.
+//
+// The viewer must not modify the value, and must not retain the pointer (or any reference type
+// reachable from it) after returning, as neither would be synchronized with writers. The viewer
+// should not call any other method of this [RWGuarded], as this may result in a deadlock.
+func (g *RWGuarded[V]) View(viewer func(*V) error) error {
+	g.rwLock.RLock("RWGuarded.View")
+	defer g.rwLock.RUnlock()
+
+	return viewer(&g.value)
+}
+
 // Set sets the underlying value.
+//
+// If this [RWGuarded] was created via [NewValidated] and the value fails validation, Set silently
+// leaves the old value in place. Use [RWGuarded.TrySet] to find out whether the value was set.
 func (g *RWGuarded[V]) Set(val V) {
-	g.rwLock.Lock()
+	g.rwLock.Lock("RWGuarded.Set")
 	defer g.rwLock.Unlock()
 
-	g.value = val
+	_ = g.setLocked(val)
+}
+
+// TrySet is like [RWGuarded.Set], but returns the validation error if this [RWGuarded] was created
+// via [NewValidated] and the value fails validation. It always returns nil otherwise.
+func (g *RWGuarded[V]) TrySet(val V) error {
+	g.rwLock.Lock("RWGuarded.TrySet")
+	defer g.rwLock.Unlock()
+
+	return g.setLocked(val)
+}
+
+// SetContext is like [RWGuarded.TrySet], but gives up and returns ctx.Err() if ctx is done before
+// the writer lock can be acquired.
+func (g *RWGuarded[V]) SetContext(ctx context.Context, val V) error {
+	if err := g.rwLock.LockContext(ctx, "RWGuarded.SetContext"); err != nil {
+		return err
+	}
+	defer g.rwLock.Unlock()
+
+	return g.setLocked(val)
+}
+
+// SetIfVersion sets the underlying value only if its version is still the provided version, as
+// obtained from [RWGuarded.GetVersioned]. Otherwise, it returns ErrVersionConflict and leaves the
+// value unchanged. Values failing validation are rejected as by [RWGuarded.TrySet].
+//
+// This allows read-compute-write cycles in which the compute step happens without holding any
+// lock, unlike with [RWGuarded.Update]. On conflict, callers typically retry the whole cycle.
+func (g *RWGuarded[V]) SetIfVersion(val V, version uint64) error {
+	g.rwLock.Lock("RWGuarded.SetIfVersion")
+	defer g.rwLock.Unlock()
+
+	if g.version != version {
+		return ErrVersionConflict
+	}
+	return g.setLocked(val)
 }
 
 // Update allows performing a read-modify-write transaction on the underlying value while holding
 // the writer lock. The updater function is passed a pointer to the underlying value, which it may
 // change in place. The error value returned from the updater is returned from this method.
 //
+// If this [RWGuarded] was created via [NewValidated] and the updated value fails validation, the
+// old value is restored and the validation error is returned. Since the old value is restored from
+// a shallow copy, updaters of validated values should replace any reference types they change
+// (e.g. assign a new map) rather than modify them in place.
+//
 // The updater should not call any other method of this [RWGuarded], as this will result in a
-// deadlock.
+// deadlock. Use [NewChecked] to detect such calls in tests.
 func (g *RWGuarded[V]) Update(updater func(*V) error) error {
-	g.rwLock.Lock()
+	g.rwLock.Lock("RWGuarded.Update")
 	defer g.rwLock.Unlock()
 
-	return updater(&g.value)
+	return g.updateLocked(updater)
 }
 
+// UpdateContext is like [RWGuarded.Update], but gives up and returns ctx.Err() without calling the
+// updater if ctx is done before the writer lock can be acquired.
+func (g *RWGuarded[V]) UpdateContext(ctx context.Context, updater func(*V) error) error {
+	if err := g.rwLock.LockContext(ctx, "RWGuarded.UpdateContext"); err != nil {
+		return err
+	}
+	defer g.rwLock.Unlock()
+
+	return g.updateLocked(updater)
+}
+
+// setLocked implements [RWGuarded.TrySet]. The caller must hold the writer lock.
+func (g *RWGuarded[V]) setLocked(val V) error {
+	if err := g.validateLocked(val); err != nil {
+		return err
+	}
+	old := g.value
+	g.value = val
+	g.commitLocked(old)
+	return nil
+}
+
+// updateLocked implements [RWGuarded.Update]. The caller must hold the writer lock.
+func (g *RWGuarded[V]) updateLocked(updater func(*V) error) error {
+	// Only copy the old value if it will be used, since it may be expensive to copy.
+	var old V
+	if g.validate != nil || len(g.watchers) > 0 {
+		old = g.value
+	}
+	if err := updater(&g.value); err != nil {
+		return err
+	}
+	if err := g.validateLocked(g.value); err != nil {
+		g.value = old
+		return err
+	}
+	g.commitLocked(old)
+	return nil
+}
+
+// validateLocked returns the error returned by the validate function for val, or nil if there is
+// no validate function. The caller must hold the writer lock.
+func (g *RWGuarded[V]) validateLocked(val V) error {
+	if g.validate == nil {
+		return nil
+	}
+	return g.validate(val)
+}
+
+// commitLocked records that the underlying value was written, given the value it replaced. The
+// caller must hold the writer lock.
+func (g *RWGuarded[V]) commitLocked(old V) {
+	g.version++
+	g.recordHistoryLocked()
+	g.notifyWatchersLocked(old)
+}
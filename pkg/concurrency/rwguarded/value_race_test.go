@@ -0,0 +1,58 @@
+//go:build race
+
+package rwguarded
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// getAliasingHelperEnv is set when running the test binary as a subprocess that performs the racy
+// accesses of TestValueGetOfReferenceTypeRaces.
+const getAliasingHelperEnv = "RWGUARDED_GET_ALIASING_HELPER"
+
+// TestValueGetOfReferenceTypeRaces documents the aliasing hazard of Get: the slice it returns
+// shares its backing array with the underlying value, so reading it after Get returns races with
+// an Update that modifies the slice in place. Since a detected race fails the test binary, the
+// racy accesses run in a subprocess, whose output must contain a race report.
+func TestValueGetOfReferenceTypeRaces(t *testing.T) {
+	if os.Getenv(getAliasingHelperEnv) != "" {
+		getOfReferenceTypeWhileUpdating()
+		return
+	}
+	t.Parallel()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestValueGetOfReferenceTypeRaces$")
+	cmd.Env = append(os.Environ(), getAliasingHelperEnv+"=1")
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Errorf("subprocess succeeded, want it to fail due to a data race")
+	}
+	if !strings.Contains(string(out), "WARNING: DATA RACE") {
+		t.Errorf("subprocess output does not contain a race report; output:\n%s", out)
+	}
+}
+
+// getOfReferenceTypeWhileUpdating reads a slice returned from Get while another goroutine modifies
+// the underlying slice in place via Update. The only synchronization between the two goroutines
+// happens before the read, so the read and the write are unordered regardless of scheduling.
+func getOfReferenceTypeWhileUpdating() {
+	rwgVal := New([]int{0})
+	gotten := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-gotten
+		_ = rwgVal.Update(func(s *[]int) error {
+			(*s)[0] = 1
+			return nil
+		})
+	}()
+
+	s := rwgVal.Get()
+	close(gotten)
+	_ = s[0]
+	<-done
+}
@@ -1,9 +1,11 @@
 package rwguarded
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestValueSetAndGet(t *testing.T) {
@@ -132,3 +134,348 @@ func TestValueUpdate(t *testing.T) {
 	}
 }
 
+func TestValueView(t *testing.T) {
+	t.Parallel()
+
+	errViewerFailed := errors.New("viewer failed")
+
+	for _, tc := range []struct {
+		name    string
+		viewErr error
+	}{
+		{
+			name:    "view_ok",
+			viewErr: nil,
+		},
+		{
+			name:    "view_error",
+			viewErr: errViewerFailed,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rwgVal := New[int](7)
+			var got int
+			err := rwgVal.View(func(val *int) error {
+				got = *val
+				return tc.viewErr
+			})
+			if err != tc.viewErr {
+				t.Errorf("View() got error %v, want %v", err, tc.viewErr)
+			}
+			if got != 7 {
+				t.Errorf("View() passed value %d, want %d", got, 7)
+			}
+		})
+	}
+}
+
+func TestValueGetOfReferenceTypeAliasesUnderlyingValue(t *testing.T) {
+	t.Parallel()
+
+	rwgVal := New([]int{0})
+	got := rwgVal.Get()
+	_ = rwgVal.Update(func(s *[]int) error {
+		(*s)[0] = 1
+		return nil
+	})
+
+	// Get only copies the slice header, so the in-place update is visible through the copy. Reading
+	// it concurrently with such an update is a data race; see TestValueGetOfReferenceTypeRaces,
+	// which only runs under the race detector.
+	if got[0] != 1 {
+		t.Errorf("Get() copy after in-place Update() got %d, want %d", got[0], 1)
+	}
+}
+
+// TestValueViewOfReferenceTypeIsRaceFree reads a map held by a RWGuarded while other goroutines
+// modify it in place via Update. Under the race detector, this test demonstrates the difference
+// between View and Get: reading the map returned from Get (e.g. `len(rwgVal.Get())` or ranging
+// over it) races with Update, since Get only copies the map header, while reading it inside View
+// does not.
+func TestValueViewOfReferenceTypeIsRaceFree(t *testing.T) {
+	t.Parallel()
+
+	rwgVal := New(map[int]int{})
+	wg := sync.WaitGroup{}
+	for i := range 8 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = rwgVal.Update(func(m *map[int]int) error {
+				(*m)[i] = i
+				return nil
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			_ = rwgVal.View(func(m *map[int]int) error {
+				sum := 0
+				for _, v := range *m {
+					sum += v
+				}
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	var gotLen int
+	_ = rwgVal.View(func(m *map[int]int) error {
+		gotLen = len(*m)
+		return nil
+	})
+	if gotLen != 8 {
+		t.Errorf("map has %d entries after updates, want 8", gotLen)
+	}
+}
+
+func TestValueSetIfVersion(t *testing.T) {
+	t.Parallel()
+
+	rwgVal := New[int](1)
+	val, version := rwgVal.GetVersioned()
+	if val != 1 || version != 0 {
+		t.Fatalf("GetVersioned() got (%d, %d), want (1, 0)", val, version)
+	}
+
+	if err := rwgVal.SetIfVersion(2, version); err != nil {
+		t.Fatalf("SetIfVersion() with current version failed with error %v", err)
+	}
+	// The version is now stale, so this write should be rejected.
+	if err := rwgVal.SetIfVersion(3, version); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("SetIfVersion() with stale version got error %v, want %v", err, ErrVersionConflict)
+	}
+	if got := rwgVal.Get(); got != 2 {
+		t.Errorf("Get() got %d, want %d", got, 2)
+	}
+
+	// Every kind of successful write should increment the version; failed updates should not.
+	rwgVal.Set(4)
+	_ = rwgVal.Update(func(val *int) error {
+		*val++
+		return nil
+	})
+	_ = rwgVal.Update(func(val *int) error {
+		return errors.New("update failed")
+	})
+	val, version = rwgVal.GetVersioned()
+	if val != 5 || version != 3 {
+		t.Errorf("GetVersioned() got (%d, %d), want (5, 3)", val, version)
+	}
+}
+
+func TestValueSetIfVersionConcurrentIncrements(t *testing.T) {
+	t.Parallel()
+
+	rwgVal := New[int](0)
+	wg := sync.WaitGroup{}
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				for {
+					val, version := rwgVal.GetVersioned()
+					if rwgVal.SetIfVersion(val+1, version) == nil {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := rwgVal.Get(); got != 1600 {
+		t.Errorf("Get() got %d, want %d", got, 1600)
+	}
+}
+
+func TestValueContextVariantsGiveUpWhileLocked(t *testing.T) {
+	t.Parallel()
+
+	rwgVal := New[int](1)
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_ = rwgVal.Update(func(val *int) error {
+			close(locked)
+			<-release
+			*val = 2
+			return nil
+		})
+	}()
+	<-locked
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := rwgVal.GetContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetContext() got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := rwgVal.SetContext(ctx, 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SetContext() got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	updaterCalled := false
+	err := rwgVal.UpdateContext(ctx, func(val *int) error {
+		updaterCalled = true
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("UpdateContext() got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if updaterCalled {
+		t.Errorf("UpdateContext() called the updater despite giving up")
+	}
+
+	// Once the lock is released, the abandoned acquisitions must not keep it held.
+	close(release)
+	got, err := rwgVal.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetContext() after release failed with error %v", err)
+	}
+	if got != 2 {
+		t.Errorf("GetContext() got %d, want %d", got, 2)
+	}
+	if err := rwgVal.SetContext(context.Background(), 4); err != nil {
+		t.Fatalf("SetContext() after release failed with error %v", err)
+	}
+	if err := rwgVal.UpdateContext(context.Background(), func(val *int) error {
+		*val++
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateContext() after release failed with error %v", err)
+	}
+	if got := rwgVal.Get(); got != 5 {
+		t.Errorf("Get() got %d, want %d", got, 5)
+	}
+}
+
+func TestValueContextVariantsWithDoneContext(t *testing.T) {
+	t.Parallel()
+
+	// Even when the lock is free, a context that is already done should be respected.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rwgVal := New[int](1)
+
+	if _, err := rwgVal.GetContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetContext() got error %v, want %v", err, context.Canceled)
+	}
+	if err := rwgVal.SetContext(ctx, 2); !errors.Is(err, context.Canceled) {
+		t.Errorf("SetContext() got error %v, want %v", err, context.Canceled)
+	}
+	if got := rwgVal.Get(); got != 1 {
+		t.Errorf("Get() got %d, want %d", got, 1)
+	}
+}
+
+// errNegative is returned by validateNonNegative for negative values.
+var errNegative = errors.New("negative value")
+
+func validateNonNegative(val int) error {
+	if val < 0 {
+		return errNegative
+	}
+	return nil
+}
+
+func TestNewValidatedRejectsInvalidInitialValue(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewValidated(-1, validateNonNegative); err != errNegative {
+		t.Errorf("NewValidated() got error %v, want %v", err, errNegative)
+	}
+}
+
+func TestValidatedWrites(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name    string
+		write   func(g *RWGuarded[int]) error
+		wantVal int
+		wantErr error
+	}{
+		{
+			name: "trysetvalid",
+			write: func(g *RWGuarded[int]) error {
+				return g.TrySet(2)
+			},
+			wantVal: 2,
+		},
+		{
+			name: "trysetinvalid",
+			write: func(g *RWGuarded[int]) error {
+				return g.TrySet(-2)
+			},
+			wantVal: 1,
+			wantErr: errNegative,
+		},
+		{
+			name: "setinvalid",
+			write: func(g *RWGuarded[int]) error {
+				g.Set(-2)
+				return nil
+			},
+			wantVal: 1,
+		},
+		{
+			name: "setcontextinvalid",
+			write: func(g *RWGuarded[int]) error {
+				return g.SetContext(context.Background(), -2)
+			},
+			wantVal: 1,
+			wantErr: errNegative,
+		},
+		{
+			name: "setifversioninvalid",
+			write: func(g *RWGuarded[int]) error {
+				return g.SetIfVersion(-2, 0)
+			},
+			wantVal: 1,
+			wantErr: errNegative,
+		},
+		{
+			name: "updatevalid",
+			write: func(g *RWGuarded[int]) error {
+				return g.Update(func(val *int) error {
+					*val++
+					return nil
+				})
+			},
+			wantVal: 2,
+		},
+		{
+			name: "updateinvalid",
+			write: func(g *RWGuarded[int]) error {
+				return g.Update(func(val *int) error {
+					*val -= 5
+					return nil
+				})
+			},
+			wantVal: 1,
+			wantErr: errNegative,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			g, err := NewValidated(1, validateNonNegative)
+			if err != nil {
+				t.Fatalf("NewValidated() failed with error %v", err)
+			}
+			if err := tc.write(g); err != tc.wantErr {
+				t.Errorf("write got error %v, want %v", err, tc.wantErr)
+			}
+			got, version := g.GetVersioned()
+			if got != tc.wantVal {
+				t.Errorf("Get() got %d, want %d", got, tc.wantVal)
+			}
+			// Rejected writes must not be recorded as writes.
+			if wantVersion := uint64(0); tc.wantVal == 1 && version != wantVersion {
+				t.Errorf("GetVersioned() got version %d, want %d", version, wantVersion)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package rwguarded
+
+import (
+	"context"
+)
+
+// change describes a single write to a [RWGuarded].
+type change[V any] struct {
+	old V
+	new V
+}
+
+// watcher buffers the most recent change to a [RWGuarded] until its consumer is ready for it.
+type watcher[V any] struct {
+	ch chan change[V]
+}
+
+func newWatcher[V any]() *watcher[V] {
+	return &watcher[V]{ch: make(chan change[V], 1)}
+}
+
+// notify delivers a change without blocking. If the previous change has not been consumed yet,
+// the two are coalesced into a single change from the oldest old value to the newest new value.
+//
+// The caller must hold the writer lock of the watched value. Since notify is the only sender on
+// the channel, this guarantees the buffer can't be refilled between the receive and send below.
+func (w *watcher[V]) notify(c change[V]) {
+	select {
+	case w.ch <- c:
+		return
+	default:
+	}
+	select {
+	case prev := <-w.ch:
+		c.old = prev.old
+	default:
+	}
+	w.ch <- c
+}
+
+// Watch returns a channel that receives the new underlying value on every successful write, such as
+// via [RWGuarded.Set], [RWGuarded.Update], or [UpdateAll]. Values are not compared, so every such
+// write is reported, even one that doesn't change the value. The channel is closed once ctx is
+// done.
+//
+// Writers never block on watchers. If the consumer falls behind, intermediate values are dropped
+// and the consumer receives the latest one once it's ready. Since only subsequent writes are
+// reported, callers that need the current value should call [RWGuarded.Get] after Watch.
+func (g *RWGuarded[V]) Watch(ctx context.Context) <-chan V {
+	w := g.addWatcher("RWGuarded.Watch")
+	out := make(chan V)
+	go func() {
+		defer close(out)
+		defer g.removeWatcher(w, "RWGuarded.Watch")
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case c := <-w.ch:
+				select {
+				case out <- c.new:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Subscribe calls fn with the previous and new underlying value on every successful write, such as
+// via [RWGuarded.Set], [RWGuarded.Update], or [UpdateAll], until ctx is done. Values are not
+// compared, so every such write is reported, even one that doesn't change the value.
+//
+// Calls to fn are made sequentially from a separate goroutine, so writers never block on
+// subscribers. If fn falls behind, consecutive writes are coalesced into a single call whose old
+// value is the value before the first of them and whose new value is the latest one.
+//
+// Since old and new are copies made the same way as by [RWGuarded.Get], the same aliasing caveats
+// apply to them.
+func (g *RWGuarded[V]) Subscribe(ctx context.Context, fn func(old, new V)) {
+	w := g.addWatcher("RWGuarded.Subscribe")
+	go func() {
+		defer g.removeWatcher(w, "RWGuarded.Subscribe")
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case c := <-w.ch:
+				fn(c.old, c.new)
+			}
+		}
+	}()
+}
+
+// addWatcher registers a new watcher on behalf of the named method.
+func (g *RWGuarded[V]) addWatcher(method string) *watcher[V] {
+	g.rwLock.Lock(method)
+	defer g.rwLock.Unlock()
+
+	w := newWatcher[V]()
+	if g.watchers == nil {
+		g.watchers = make(map[*watcher[V]]struct{})
+	}
+	g.watchers[w] = struct{}{}
+	return w
+}
+
+// removeWatcher unregisters a watcher on behalf of the named method.
+func (g *RWGuarded[V]) removeWatcher(w *watcher[V], method string) {
+	g.rwLock.Lock(method)
+	defer g.rwLock.Unlock()
+
+	delete(g.watchers, w)
+}
+
+// notifyWatchersLocked reports a write to all watchers. The caller must hold the writer lock.
+func (g *RWGuarded[V]) notifyWatchersLocked(old V) {
+	for w := range g.watchers {
+		w.notify(change[V]{old: old, new: g.value})
+	}
+}
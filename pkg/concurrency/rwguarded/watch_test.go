@@ -0,0 +1,124 @@
+package rwguarded
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// receive returns the next value from ch, failing the test if none arrives within a few seconds.
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting to receive from channel")
+	}
+	panic("unreachable")
+}
+
+func TestValueWatch(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rwgVal := New[int](0)
+	ch := rwgVal.Watch(ctx)
+
+	rwgVal.Set(1)
+	if got := receive(t, ch); got != 1 {
+		t.Errorf("Watch() after Set() got %d, want %d", got, 1)
+	}
+
+	_ = rwgVal.Update(func(val *int) error {
+		*val += 10
+		return nil
+	})
+	if got := receive(t, ch); got != 11 {
+		t.Errorf("Watch() after Update() got %d, want %d", got, 11)
+	}
+
+	// A failed update should not be reported.
+	_ = rwgVal.Update(func(val *int) error {
+		return errors.New("update failed")
+	})
+	rwgVal.Set(12)
+	if got := receive(t, ch); got != 12 {
+		t.Errorf("Watch() after failed Update() and Set() got %d, want %d", got, 12)
+	}
+
+	cancel()
+	for range ch {
+	}
+}
+
+func TestValueWatchCoalescesForSlowConsumer(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rwgVal := New[int](0)
+	ch := rwgVal.Watch(ctx)
+
+	// None of these writes should block, even though nothing is receiving yet.
+	for i := 1; i <= 100; i++ {
+		rwgVal.Set(i)
+	}
+
+	// The forwarding goroutine may already hold an intermediate value, but the latest value must
+	// arrive without any further writes.
+	for got := receive(t, ch); got != 100; got = receive(t, ch) {
+	}
+}
+
+func TestValueSubscribe(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rwgVal := New[string]("a")
+
+	type oldNew struct {
+		old, new string
+	}
+	changes := make(chan oldNew, 8)
+	rwgVal.Subscribe(ctx, func(old, new string) {
+		changes <- oldNew{old: old, new: new}
+	})
+
+	rwgVal.Set("b")
+	if got, want := receive(t, changes), (oldNew{old: "a", new: "b"}); got != want {
+		t.Errorf("Subscribe() after Set() got %+v, want %+v", got, want)
+	}
+	_ = rwgVal.Update(func(val *string) error {
+		*val += "c"
+		return nil
+	})
+	if got, want := receive(t, changes), (oldNew{old: "b", new: "bc"}); got != want {
+		t.Errorf("Subscribe() after Update() got %+v, want %+v", got, want)
+	}
+}
+
+func TestValueWatchStopsWhenContextDone(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rwgVal := New[int](0)
+	ch := rwgVal.Watch(ctx)
+	cancel()
+
+	for range ch {
+	}
+	// The watcher is removed before the channel is closed.
+	var count int
+	_ = rwgVal.View(func(*int) error {
+		count = len(rwgVal.watchers)
+		return nil
+	})
+	if count != 0 {
+		t.Errorf("got %d watchers after context was done, want 0", count)
+	}
+}